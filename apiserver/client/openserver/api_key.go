@@ -1,6 +1,7 @@
 package openserver
 
 import (
+	"common/types"
 	"context"
 	"time"
 )
@@ -10,15 +11,36 @@ import (
 type KeyInfoRequest struct {
 	ID             string `form:"id"`
 	WithUsageLimit bool   `form:"withUsageLimit"`
+	WithBalance    bool   `form:"withBalance"`
+	WithUserLimit  bool   `form:"withUserLimit"`
 }
 
 type KeyInfoResponse struct {
-	ID          string       `json:"id"`
-	UserID      string       `json:"userID"`
-	WorkspaceID string       `json:"workspaceID"`
-	Description string       `json:"description,omitempty"`
-	ExpiresAt   *time.Time   `json:"expiresAt,omitempty"`
-	UsageLimits []UsageLimit `json:"usageLimits,omitempty"`
+	ID          string        `json:"id"`
+	UserID      string        `json:"userID"`
+	WorkspaceID string        `json:"workspaceID"`
+	Tier        int           `json:"tier"` // 工作空间服务等级
+	Description string        `json:"description,omitempty"`
+	ExpiresAt   *time.Time    `json:"expiresAt,omitempty"`
+	UsageLimits []UsageLimit  `json:"usageLimits,omitempty"`
+	UserLimit   *UserLimit    `json:"userLimit,omitempty"` // 用户汇总调用限制
+	Balance     *types.Amount `json:"balance,omitempty"`   // 预付费余额，未启用预付费且未开户时为空
+
+	ApiKeyRestriction `json:",inline"` // 密钥自身的限制
+}
+
+// 密钥限制，字段为空表示不限制
+type ApiKeyRestriction struct {
+	Scopes       []string `json:"scopes,omitempty"`       // 允许调用的接口
+	Models       []string `json:"models,omitempty"`       // 允许调用的模型
+	AllowedIPs   []string `json:"allowedIPs,omitempty"`   // 允许的来源地址(CIDR)
+	RequestLimit int64    `json:"requestLimit,omitempty"` // 请求数限流（次/分钟）
+	TokenLimit   int64    `json:"tokenLimit,omitempty"`   // Token限流（Tokens/分钟）
+}
+
+type UserLimit struct {
+	RequestLimit int64 `json:"requestLimit"`
+	TokenLimit   int64 `json:"tokenLimit"`
 }
 
 type UsageLimit struct {
@@ -28,7 +50,7 @@ type UsageLimit struct {
 }
 
 func FindApiKey(ctx context.Context, id string) (*KeyInfoResponse, error) {
-	request := KeyInfoRequest{ID: id, WithUsageLimit: true}
+	request := KeyInfoRequest{ID: id, WithUsageLimit: true, WithBalance: true, WithUserLimit: true}
 	var resp KeyInfoResponse
 	if err := Get(ctx, "/v1/gateway/key/info", request, &resp); err != nil {
 		return nil, err
@@ -0,0 +1,22 @@
+package openserver
+
+import (
+	"context"
+)
+
+// 查询模型别名
+
+type ModelAliasResponse struct {
+	Alias           string `json:"alias"`
+	ModelName       string `json:"modelName"`
+	RewriteResponse bool   `json:"rewriteResponse"` // 响应中的 model 字段改写为别名
+}
+
+func FindModelAliases(ctx context.Context) ([]ModelAliasResponse, error) {
+	response := []ModelAliasResponse{}
+	if err := Get(ctx, "/v1/gateway/model/aliases", nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,33 @@
+package openserver
+
+import (
+	"apiserver/config"
+	"context"
+	"time"
+)
+
+// 查询自己负责的模型元数据
+
+type ModelInfosRequest struct {
+	ID string `form:"id"`
+}
+
+type ModelInfoResponse struct {
+	Name             string    `json:"name"`
+	ProviderName     string    `json:"providerName,omitempty"`
+	Classes          []uint64  `json:"classes,omitempty"`
+	Abilities        []uint64  `json:"abilities,omitempty"`
+	MaxContextLength uint64    `json:"maxContextLength"`
+	Description      string    `json:"description,omitempty"`
+	CreatedAt        time.Time `json:"createAt"`
+}
+
+func FindModelInfos(ctx context.Context) ([]ModelInfoResponse, error) {
+	request := ModelInfosRequest{ID: config.GetZdan().ApiServiceId}
+	response := []ModelInfoResponse{}
+	if err := Get(ctx, "/v1/gateway/model/infos", request, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -12,11 +12,13 @@ type ModelServicesRequest struct {
 }
 
 type ModelServicesResponse struct {
-	ID        string           `json:"id"`
-	ModelName string           `json:"modelName"`
-	Power     uint64           `json:"power"`
-	Load      uint64           `json:"load"`
-	Targets   []*ServiceTarget `json:"targets"`
+	ID          string           `json:"id"`
+	ModelName   string           `json:"modelName"`
+	Engine      string           `json:"engine,omitempty"` // 推理引擎名称
+	Power       uint64           `json:"power"`
+	Load        uint64           `json:"load"`
+	MaxInflight int64            `json:"maxInflight,omitempty"` // 最多同时处理的请求数，为0时按算力推算
+	Targets     []*ServiceTarget `json:"targets"`
 }
 
 type ServiceTarget struct {
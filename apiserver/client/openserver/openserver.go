@@ -8,10 +8,12 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-playground/form"
@@ -19,6 +21,10 @@ import (
 
 var (
 	shareTransport *http.Transport
+
+	// 最近一次访问开放平台成功和失败的时间(毫秒)，开放平台返回业务错误也算可达
+	lastSuccess atomic.Int64
+	lastFailure atomic.Int64
 )
 
 func init() {
@@ -31,6 +37,19 @@ func init() {
 
 }
 
+// 开放平台是否不可达，最近一次访问失败
+func Unreachable() bool {
+	return lastFailure.Load() > lastSuccess.Load()
+}
+
+// 最近一次访问开放平台成功的时间，从未成功时为零值
+func LastSuccess() time.Time {
+	if ms := lastSuccess.Load(); ms > 0 {
+		return time.UnixMilli(ms)
+	}
+	return time.Time{}
+}
+
 func Get(ctx context.Context, endpoint string, param, resp any) error {
 	return do(ctx, "GET", endpoint, param, nil, resp)
 }
@@ -40,6 +59,19 @@ func Post(ctx context.Context, endpoint string, data, resp any) error {
 }
 
 func do(ctx context.Context, method, endpoint string, param, data, resp any) error {
+	err := request(ctx, method, endpoint, param, data, resp)
+
+	var codeErr *common.Error
+	if err == nil || errors.As(err, &codeErr) {
+		lastSuccess.Store(time.Now().UnixMilli())
+	} else if ctx.Err() == nil {
+		lastFailure.Store(time.Now().UnixMilli())
+	}
+
+	return err
+}
+
+func request(ctx context.Context, method, endpoint string, param, data, resp any) error {
 
 	zdan := config.GetZdan()
 	fullUrl, err := url.JoinPath(zdan.OpenBaseURL, endpoint)
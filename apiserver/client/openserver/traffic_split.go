@@ -0,0 +1,39 @@
+package openserver
+
+import (
+	"apiserver/config"
+	"context"
+)
+
+// 查询自己负责的模型的流量分配规则
+
+type ModelSplitsRequest struct {
+	ID string `form:"id"`
+}
+
+type ModelSplitResponse struct {
+	ModelName string             `json:"modelName"`
+	Routes    []*ModelSplitRoute `json:"routes,omitempty"`
+	Sticky    bool               `json:"sticky"` // 按API密钥保持分配结果
+	Shadow    *ModelSplitShadow  `json:"shadow,omitempty"`
+}
+
+type ModelSplitRoute struct {
+	ServiceIDs []string `json:"serviceIDs"`
+	Weight     uint64   `json:"weight"`
+}
+
+type ModelSplitShadow struct {
+	ServiceIDs []string `json:"serviceIDs"`
+	Percent    float64  `json:"percent"` // 镜像比例，0-100
+}
+
+func FindModelSplits(ctx context.Context) ([]ModelSplitResponse, error) {
+	request := ModelSplitsRequest{ID: config.GetZdan().ApiServiceId}
+	response := []ModelSplitResponse{}
+	if err := Get(ctx, "/v1/gateway/model/splits", request, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
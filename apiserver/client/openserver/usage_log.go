@@ -0,0 +1,79 @@
+package openserver
+
+import (
+	"apiserver/config"
+	"context"
+	"encoding/json"
+)
+
+// 上报调用日志
+
+type UsageReportRequest struct {
+	ApiServiceID string         `json:"apiServiceID"`
+	BatchID      string         `json:"batchID"` // 重试时不变，开放平台据此去重
+	KeyUsageLogs []KeyUsageLogs `json:"keyUsageLogs"`
+}
+
+type KeyUsageLogs struct {
+	ApiKey      string     `json:"apiKey"`
+	UserID      string     `json:"userID"`
+	WorkspaceID string     `json:"workspaceID"`
+	LastUsedIP  string     `json:"lastUsedIP,omitempty"` // 最近调用的来源地址
+	UsageLogs   []UsageLog `json:"usageLogs"`
+}
+
+type UsageLog struct {
+	Timestamp    int64  `json:"timestamp"` // 调用时间(毫秒)
+	ModelName    string `json:"modelName"`
+	ServiceID    string `json:"serviceID,omitempty"`
+	Target       string `json:"target,omitempty"` // 最终处理请求的副本地址
+	Attempts     int    `json:"attempts"`         // 转发尝试次数
+	Status       int    `json:"status"`
+	InputTokens  int64  `json:"inputTokens"`
+	OutputTokens int64  `json:"outputTokens"`
+	CachedTokens int64  `json:"cachedTokens,omitempty"` // 命中缓存的输入token
+	ResponseTime int64  `json:"responseTime"`           // 响应耗时(毫秒)
+
+	// 非token计费单位
+	ImageCount  int64 `json:"imageCount,omitempty"`  // 生成图片数量
+	ImagePixels int64 `json:"imagePixels,omitempty"` // 生成图片总像素
+	AudioMillis int64 `json:"audioMillis,omitempty"` // 音频时长(毫秒)
+	Characters  int64 `json:"characters,omitempty"`  // 语音合成的字符数
+	Estimated   bool  `json:"estimated,omitempty"`   // 使用量由网关统计
+
+	// 流式响应统计
+	Stream          bool    `json:"stream,omitempty"`
+	FirstTokenTime  int64   `json:"firstTokenTime,omitempty"`  // 首个内容块耗时(毫秒)
+	StreamDuration  int64   `json:"streamDuration,omitempty"`  // 流式响应总耗时(毫秒)
+	TokensPerSecond float64 `json:"tokensPerSecond,omitempty"` // 输出速度
+	Disconnected    bool    `json:"disconnected,omitempty"`    // 客户端中途断开
+}
+
+func ReportUsageLogs(ctx context.Context, batchID string, keyUsageLogs []KeyUsageLogs) error {
+	request := UsageReportRequest{
+		ApiServiceID: config.GetZdan().ApiServiceId,
+		BatchID:      batchID,
+		KeyUsageLogs: keyUsageLogs,
+	}
+	return Post(ctx, "/v1/gateway/usage/report", request, nil)
+}
+
+// 查询密钥所属工作空间的使用量汇总
+
+type UsageSummaryRequest struct {
+	ID        string `form:"id"`
+	GroupBy   string `form:"groupBy,omitempty"`
+	ModelName string `form:"modelName,omitempty"`
+	StartTime string `form:"startTime,omitempty"`
+	EndTime   string `form:"endTime,omitempty"`
+	PageIndex int    `form:"pageIndex,omitempty"`
+	PageSize  int    `form:"pageSize,omitempty"`
+}
+
+func UsageSummary(ctx context.Context, request *UsageSummaryRequest) (json.RawMessage, error) {
+	var resp json.RawMessage
+	if err := Get(ctx, "/v1/gateway/usage/summary", request, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
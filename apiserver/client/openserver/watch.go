@@ -0,0 +1,42 @@
+package openserver
+
+import (
+	"context"
+	"time"
+)
+
+// 监听开放平台的变更事件
+
+type WatchRequest struct {
+	Revision int64 `form:"revision"`
+	Timeout  int   `form:"timeout"`
+}
+
+type ChangeSet struct {
+	Revision int64         `json:"revision"`
+	Reset    bool          `json:"reset,omitempty"` // 事件已被清理，需要清空全部缓存
+	Events   []ChangeEvent `json:"events,omitempty"`
+}
+
+type ChangeEvent struct {
+	Revision int64  `json:"revision"`
+	Kind     string `json:"kind"`
+	Target   string `json:"target,omitempty"`
+}
+
+// 变更类型
+const (
+	ChangeKey       = "key"
+	ChangeWorkspace = "workspace"
+	ChangeUser      = "user"
+	ChangeService   = "service"
+)
+
+func Watch(ctx context.Context, revision int64, timeout time.Duration) (*ChangeSet, error) {
+	request := WatchRequest{Revision: revision, Timeout: int(timeout.Seconds())}
+	var resp ChangeSet
+	if err := Get(ctx, "/v1/gateway/watch", request, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
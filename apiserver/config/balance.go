@@ -0,0 +1,52 @@
+package config
+
+import "fmt"
+
+// 负载均衡策略
+const (
+	BalanceRoundRobin    = "round_robin"    // 轮询
+	BalanceWeighted      = "weighted"       // 按算力加权轮询
+	BalanceLeastInflight = "least_inflight" // 最少处理中请求
+	BalanceP2C           = "p2c"            // 随机两选一
+	BalanceHash          = "hash"           // 按会话一致性哈希
+)
+
+type BalanceConfig struct {
+	Strategy string            `yaml:"strategy"` // 默认策略
+	Models   map[string]string `yaml:"models"`   // 模型名称对应策略
+}
+
+func (c *BalanceConfig) Check() error {
+
+	if c.Strategy == "" {
+		c.Strategy = BalanceRoundRobin
+	}
+
+	if !isBalanceStrategy(c.Strategy) {
+		return fmt.Errorf("invalid balance strategy: %s", c.Strategy)
+	}
+
+	for modelName, strategy := range c.Models {
+		if !isBalanceStrategy(strategy) {
+			return fmt.Errorf("invalid balance strategy for %s: %s", modelName, strategy)
+		}
+	}
+
+	return nil
+}
+
+// 获取模型的负载均衡策略
+func (c *BalanceConfig) GetStrategy(modelName string) string {
+	if strategy, ok := c.Models[modelName]; ok {
+		return strategy
+	}
+	return c.Strategy
+}
+
+func isBalanceStrategy(strategy string) bool {
+	switch strategy {
+	case BalanceRoundRobin, BalanceWeighted, BalanceLeastInflight, BalanceP2C, BalanceHash:
+		return true
+	}
+	return false
+}
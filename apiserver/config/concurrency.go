@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+type ConcurrencyConfig struct {
+	InflightPerPower int64         `yaml:"inflightPerPower"` // 未配置并发上限时，每单位算力允许的并发请求数，为0时不限制
+	QueueSize        int           `yaml:"queueSize"`        // 每个服务最多排队的请求数
+	QueueTimeout     time.Duration `yaml:"queueTimeout"`     // 排队超时
+}
+
+func (c *ConcurrencyConfig) Check() error {
+
+	if c.InflightPerPower < 0 {
+		c.InflightPerPower = 0
+	}
+
+	if c.QueueSize <= 0 {
+		c.QueueSize = 100
+	}
+
+	if c.QueueTimeout <= 0 {
+		c.QueueTimeout = 30 * time.Second
+	}
+
+	return nil
+}
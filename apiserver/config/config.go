@@ -14,8 +14,20 @@ var (
 )
 
 type Config struct {
-	Log  logger.Config `yaml:"log"`
-	Zdan ZdanConfig    `yaml:"zdan"`
+	Log         logger.Config     `yaml:"log"`
+	Zdan        ZdanConfig        `yaml:"zdan"`
+	Server      ServerConfig      `yaml:"server"`
+	Balance     BalanceConfig     `yaml:"balance"`
+	Health      HealthConfig      `yaml:"health"`
+	Retry       RetryConfig       `yaml:"retry"`
+	Shadow      ShadowConfig      `yaml:"shadow"`
+	Tokenizer   TokenizerConfig   `yaml:"tokenizer"`
+	Limiter     LimiterConfig     `yaml:"limiter"`
+	Concurrency ConcurrencyConfig `yaml:"concurrency"`
+	Watch       WatchConfig       `yaml:"watch"`
+	KeyCache    KeyCacheConfig    `yaml:"keyCache"`
+	Snapshot    SnapshotConfig    `yaml:"snapshot"`
+	Secure      SecureConfig      `yaml:"secure"`
 }
 
 func (c *Config) Check() error {
@@ -24,10 +36,63 @@ func (c *Config) Check() error {
 		c.Log = logger.DefaultConfig()
 	}
 
+	if err := c.Secure.Check(); err != nil {
+		return err
+	}
+
+	// 密钥引用从密钥提供者读取
+	if err := c.Secure.ResolveSecrets(&c.Zdan.ApiServerKey, &c.Limiter.Redis.Password); err != nil {
+		return err
+	}
+
 	if err := c.Zdan.Check(); err != nil {
 		return err
 	}
 
+	if err := c.Server.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Balance.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Health.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Retry.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Shadow.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Tokenizer.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Limiter.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Concurrency.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Watch.Check(); err != nil {
+		return err
+	}
+
+	if err := c.KeyCache.Check(); err != nil {
+		return err
+	}
+
+	if err := c.Snapshot.Check(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -43,6 +108,50 @@ func GetZdan() *ZdanConfig {
 	return &config.Zdan
 }
 
+func GetServer() *ServerConfig {
+	return &config.Server
+}
+
+func GetBalance() *BalanceConfig {
+	return &config.Balance
+}
+
+func GetHealth() *HealthConfig {
+	return &config.Health
+}
+
+func GetRetry() *RetryConfig {
+	return &config.Retry
+}
+
+func GetShadow() *ShadowConfig {
+	return &config.Shadow
+}
+
+func GetTokenizer() *TokenizerConfig {
+	return &config.Tokenizer
+}
+
+func GetLimiter() *LimiterConfig {
+	return &config.Limiter
+}
+
+func GetConcurrency() *ConcurrencyConfig {
+	return &config.Concurrency
+}
+
+func GetWatch() *WatchConfig {
+	return &config.Watch
+}
+
+func GetKeyCache() *KeyCacheConfig {
+	return &config.KeyCache
+}
+
+func GetSnapshot() *SnapshotConfig {
+	return &config.Snapshot
+}
+
 func Load(filename string) error {
 
 	if !filepath.IsAbs(filename) {
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type HealthConfig struct {
+	Path             string        `yaml:"path"`             // 探测路径
+	Interval         time.Duration `yaml:"interval"`         // 探测间隔
+	Timeout          time.Duration `yaml:"timeout"`          // 探测超时
+	FailureThreshold int           `yaml:"failureThreshold"` // 连续失败多少次后摘除
+	BaseEjection     time.Duration `yaml:"baseEjection"`     // 首次摘除时长，再次摘除时翻倍
+	MaxEjection      time.Duration `yaml:"maxEjection"`      // 最长摘除时长
+}
+
+func (c *HealthConfig) Check() error {
+
+	if c.Path == "" {
+		c.Path = "/health"
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = 10 * time.Second
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 3 * time.Second
+	}
+
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 3
+	}
+
+	if c.BaseEjection <= 0 {
+		c.BaseEjection = 10 * time.Second
+	}
+
+	if c.MaxEjection <= 0 {
+		c.MaxEjection = 5 * time.Minute
+	}
+
+	if c.MaxEjection < c.BaseEjection {
+		return fmt.Errorf("health maxEjection must not be less than baseEjection")
+	}
+
+	return nil
+}
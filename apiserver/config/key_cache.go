@@ -0,0 +1,41 @@
+package config
+
+import "time"
+
+type KeyCacheConfig struct {
+	Size          int           `yaml:"size"`          // 最多缓存的密钥数量，超出时淘汰最久未使用的
+	TTL           time.Duration `yaml:"ttl"`           // 有效密钥的缓存时长
+	NegativeTTL   time.Duration `yaml:"negativeTTL"`   // 无效或已停用密钥的缓存时长
+	StaleTTL      time.Duration `yaml:"staleTTL"`      // 有效密钥过期后仍可使用的时长，期间先返回旧数据再后台刷新
+	FailureLimit  int           `yaml:"failureLimit"`  // 每个来源地址在窗口内允许的认证失败次数
+	FailureWindow time.Duration `yaml:"failureWindow"` // 认证失败计数窗口，超出次数后窗口结束前拒绝该地址
+}
+
+func (c *KeyCacheConfig) Check() error {
+
+	if c.Size <= 0 {
+		c.Size = 100000
+	}
+
+	if c.TTL <= 0 {
+		c.TTL = 180 * time.Second
+	}
+
+	if c.NegativeTTL <= 0 {
+		c.NegativeTTL = 10 * time.Second
+	}
+
+	if c.StaleTTL <= 0 {
+		c.StaleTTL = 24 * time.Hour
+	}
+
+	if c.FailureLimit <= 0 {
+		c.FailureLimit = 20
+	}
+
+	if c.FailureWindow <= 0 {
+		c.FailureWindow = time.Minute
+	}
+
+	return nil
+}
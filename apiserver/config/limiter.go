@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// 限流后端
+const (
+	LimiterMemory = "memory" // 进程内计数，只对单个副本生效
+	LimiterRedis  = "redis"  // Redis令牌桶，多个副本共享限额
+)
+
+type LimiterConfig struct {
+	Backend string      `yaml:"backend"` // 限流后端
+	Redis   RedisConfig `yaml:"redis"`   // Redis后端配置
+}
+
+type RedisConfig struct {
+	Addr     string        `yaml:"addr"`     // 地址
+	Password string        `yaml:"password"` // 密码
+	DB       int           `yaml:"db"`       // 数据库
+	Prefix   string        `yaml:"prefix"`   // 键前缀，多套环境共用时区分
+	Timeout  time.Duration `yaml:"timeout"`  // 读写超时，超时后改用进程内限流
+}
+
+func (c *LimiterConfig) Check() error {
+
+	if c.Backend == "" {
+		c.Backend = LimiterMemory
+	}
+
+	switch c.Backend {
+	case LimiterMemory:
+	case LimiterRedis:
+		if c.Redis.Addr == "" {
+			return fmt.Errorf("limiter redis addr is required")
+		}
+	default:
+		return fmt.Errorf("invalid limiter backend: %s", c.Backend)
+	}
+
+	if c.Redis.Prefix == "" {
+		c.Redis.Prefix = "zdan:limiter:"
+	}
+
+	if c.Redis.Timeout <= 0 {
+		c.Redis.Timeout = 200 * time.Millisecond
+	}
+
+	return nil
+}
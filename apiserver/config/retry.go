@@ -0,0 +1,14 @@
+package config
+
+type RetryConfig struct {
+	MaxAttempts int `yaml:"maxAttempts"` // 最多尝试次数，包含首次转发
+}
+
+func (c *RetryConfig) Check() error {
+
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+
+	return nil
+}
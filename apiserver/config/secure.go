@@ -0,0 +1,51 @@
+package config
+
+import (
+	"common/secure"
+	"context"
+	"time"
+)
+
+type SecureConfig struct {
+	Provider   secure.ProviderConfig `yaml:"provider"`   // 密钥提供者，配置中 secret:<名称> 形式的值从提供者读取
+	MasterKeys secure.KeyringConfig  `yaml:"masterKeys"` // 解密配置中 enc: 形式密文的主密钥版本
+	provider   secure.KeyProvider
+	keyring    *secure.Keyring
+}
+
+func (c *SecureConfig) Check() error {
+
+	if err := c.Provider.Check(); err != nil {
+		return err
+	}
+	c.provider = secure.NewKeyProvider(c.Provider)
+
+	if err := c.MasterKeys.Check(); err != nil {
+		return err
+	}
+
+	if c.MasterKeys.Current != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		keyring, err := secure.NewKeyring(ctx, c.provider, c.MasterKeys)
+		if err != nil {
+			return err
+		}
+		c.keyring = keyring
+	}
+
+	return nil
+}
+
+// 解析配置中的密钥引用，enc: 形式的密文使用主密钥解密
+func (c *SecureConfig) ResolveSecrets(values ...*string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := secure.ResolveSecrets(ctx, c.provider, values...); err != nil {
+		return err
+	}
+
+	return secure.DecryptSecrets(c.keyring, values...)
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+type ServerConfig struct {
+	TrustedProxies []string `yaml:"trustedProxies"` // 信任的代理地址或网段，只有来自这些地址的请求才使用 X-Forwarded-For 等请求头中的来源地址
+}
+
+func (c *ServerConfig) Check() error {
+
+	for _, proxy := range c.TrustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("invalid trusted proxy: %s", proxy)
+			}
+		} else if net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy: %s", proxy)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+type ShadowConfig struct {
+	MaxInflight int           `yaml:"maxInflight"` // 同时进行的镜像请求上限，超出时丢弃
+	Timeout     time.Duration `yaml:"timeout"`     // 单个镜像请求的超时时间
+}
+
+func (c *ShadowConfig) Check() error {
+
+	if c.MaxInflight <= 0 {
+		c.MaxInflight = 32
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 300 * time.Second
+	}
+
+	return nil
+}
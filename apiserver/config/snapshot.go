@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+type SnapshotConfig struct {
+	Path     string        `yaml:"path"`     // 快照文件，为空时不保存
+	Interval time.Duration `yaml:"interval"` // 保存间隔
+	MaxAge   time.Duration `yaml:"maxAge"`   // 启动时忽略超过此时长的快照
+}
+
+func (c *SnapshotConfig) Check() error {
+
+	if c.Interval <= 0 {
+		c.Interval = 60 * time.Second
+	}
+
+	if c.MaxAge <= 0 {
+		c.MaxAge = 24 * time.Hour
+	}
+
+	return nil
+}
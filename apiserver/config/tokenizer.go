@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// 上游未返回使用量时的计数方式
+const (
+	TokenizerEngine   = "engine"   // 调用推理引擎的 /tokenize，失败时估算
+	TokenizerEstimate = "estimate" // 只按字符估算
+)
+
+type TokenizerConfig struct {
+	Mode    string        `yaml:"mode"`    // 计数方式
+	Timeout time.Duration `yaml:"timeout"` // 调用推理引擎超时
+}
+
+func (c *TokenizerConfig) Check() error {
+
+	if c.Mode == "" {
+		c.Mode = TokenizerEngine
+	}
+
+	if c.Mode != TokenizerEngine && c.Mode != TokenizerEstimate {
+		return fmt.Errorf("invalid tokenizer mode: %s", c.Mode)
+	}
+
+	if c.Timeout <= 0 {
+		c.Timeout = 2 * time.Second
+	}
+
+	return nil
+}
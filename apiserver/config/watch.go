@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+type WatchConfig struct {
+	Disabled      bool          `yaml:"disabled"`      // 关闭后只依靠定时刷新
+	Timeout       time.Duration `yaml:"timeout"`       // 单次监听最长等待时间，须小于开放平台请求超时
+	RetryInterval time.Duration `yaml:"retryInterval"` // 监听失败后的重试间隔
+}
+
+func (c *WatchConfig) Check() error {
+
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+
+	if c.Timeout < time.Second || c.Timeout > 12*time.Second {
+		return fmt.Errorf("watch timeout must be between 1s and 12s")
+	}
+
+	if c.RetryInterval <= 0 {
+		c.RetryInterval = 5 * time.Second
+	}
+
+	return nil
+}
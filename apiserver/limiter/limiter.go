@@ -0,0 +1,151 @@
+package limiter
+
+import (
+	"apiserver/config"
+	"common/logger"
+	"context"
+	"time"
+)
+
+// 限流窗口，限额均为每分钟
+const window = time.Minute
+
+// 限流规则
+type Rule struct {
+	Key          string
+	Scope        string // 限流层级
+	RequestLimit int64
+	TokenLimit   int64
+}
+
+// 限流层级
+const (
+	ScopeUser      = "user"
+	ScopeWorkspace = "workspace"
+	ScopeKey       = "key"
+)
+
+// 限流检查结果
+type Result struct {
+	Allowed          bool
+	Scope            string // 超限的层级
+	RequestLimit     int64
+	RequestRemaining int64
+	RequestReset     time.Duration
+	TokenLimit       int64
+	TokenRemaining   int64
+	TokenReset       time.Duration
+}
+
+// 需要等待的时间
+func (r *Result) RetryAfter() time.Duration {
+	var wait time.Duration
+	if r.RequestLimit > 0 && r.RequestRemaining <= 0 {
+		wait = r.RequestReset
+	}
+	if r.TokenLimit > 0 && r.TokenRemaining <= 0 && r.TokenReset > wait {
+		wait = r.TokenReset
+	}
+	return wait
+}
+
+// 合并各层级结果，请求数与Token数分别取剩余最少的层级
+func mergeResults(results []*Result) *Result {
+	merged := &Result{Allowed: true}
+	for _, result := range results {
+		if result.RequestLimit > 0 && (merged.RequestLimit <= 0 || result.RequestRemaining < merged.RequestRemaining) {
+			merged.RequestLimit = result.RequestLimit
+			merged.RequestRemaining = result.RequestRemaining
+			merged.RequestReset = result.RequestReset
+		}
+		if result.TokenLimit > 0 && (merged.TokenLimit <= 0 || result.TokenRemaining < merged.TokenRemaining) {
+			merged.TokenLimit = result.TokenLimit
+			merged.TokenRemaining = result.TokenRemaining
+			merged.TokenReset = result.TokenReset
+		}
+	}
+	return merged
+}
+
+// 限流后端
+type Backend interface {
+	Allow(ctx context.Context, rules []Rule) (*Result, error)      // 分层检查，全部通过才占用请求
+	Consume(ctx context.Context, tokens int64, rules []Rule) error // 扣减Token用量
+	Cleanup()                                                      // 清理过期计数
+}
+
+var (
+	backend Backend
+)
+
+func init() {
+	backend = NewMemoryBackend(window)
+}
+
+// 按配置初始化限流后端
+func Init(ctx context.Context) {
+	cfg := config.GetLimiter()
+	if cfg.Backend != config.LimiterRedis {
+		return
+	}
+
+	redisBackend := NewRedisBackend(cfg.Redis, window)
+	if err := redisBackend.Ping(ctx); err != nil {
+		logger.Warn("Limiter redis unavailable, limits apply per replica until it recovers", logger.String("addr", cfg.Redis.Addr), logger.Err(err))
+	}
+
+	backend = redisBackend
+}
+
+// 分层检查限流，后端不可用时放行
+func AllowAll(ctx context.Context, rules ...Rule) *Result {
+	result, err := backend.Allow(ctx, rules)
+	if err != nil {
+		logger.Warn("Limiter allow failed", logger.Err(err))
+		return &Result{Allowed: true}
+	}
+	return result
+}
+
+// 扣减Token
+func Consume(ctx context.Context, tokens int64, rules ...Rule) {
+	if err := backend.Consume(ctx, tokens, rules); err != nil {
+		logger.Warn("Limiter consume failed", logger.Err(err))
+	}
+}
+
+// 用户限流键，汇总用户所有工作空间的调用
+func UserKey(userID string) string {
+	return "user:" + userID
+}
+
+// 密钥限流键，使用密钥的查找哈希
+func KeyKey(keyID string) string {
+	return "key:" + keyID
+}
+
+// 工作空间模型限流键
+func WorkspaceKey(workspaceID, modelName string) string {
+	return "ws:" + workspaceID + ":" + modelName
+}
+
+// 清理限流计数器任务
+func CleanupTask(ctx context.Context) {
+
+	logger.Info("Limiter background task start")
+
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			backend.Cleanup()
+		case <-ctx.Done():
+			goto end
+		}
+	}
+
+end:
+	logger.Info("Limiter background task final")
+}
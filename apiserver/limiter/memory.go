@@ -0,0 +1,120 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 进程内限流后端，滑动窗口计数，只对单个副本生效
+
+// 限流计数器，请求数与Token数分别计数
+type Counter struct {
+	Requests *Window
+	Tokens   *Window
+}
+
+type MemoryBackend struct {
+	mutex    sync.Mutex
+	size     time.Duration
+	counters map[string]*Counter
+}
+
+func NewMemoryBackend(size time.Duration) *MemoryBackend {
+	return &MemoryBackend{size: size, counters: make(map[string]*Counter)}
+}
+
+func (l *MemoryBackend) counter(key string) *Counter {
+	found := l.counters[key]
+	if found == nil {
+		found = &Counter{Requests: NewWindow(l.size), Tokens: NewWindow(l.size)}
+		l.counters[key] = found
+	}
+	return found
+}
+
+// 分层检查所有限流规则，全部通过才占用请求
+func (l *MemoryBackend) Allow(ctx context.Context, rules []Rule) (*Result, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	results := make([]*Result, len(rules))
+	for i, rule := range rules {
+		results[i] = l.check(now, rule)
+		if !results[i].Allowed {
+			return results[i], nil
+		}
+	}
+
+	for i, rule := range rules {
+		counter := l.counter(rule.Key)
+		counter.Requests.Add(now, 1)
+		if rule.RequestLimit > 0 {
+			results[i].RequestRemaining--
+			results[i].RequestReset = counter.Requests.ResetAfter(now, rule.RequestLimit)
+		}
+	}
+
+	return mergeResults(results), nil
+}
+
+// 检查单条规则，不占用请求
+func (l *MemoryBackend) check(now time.Time, rule Rule) *Result {
+	counter := l.counter(rule.Key)
+	requests := counter.Requests.Count(now)
+	tokens := counter.Tokens.Count(now)
+
+	result := &Result{
+		Allowed:          true,
+		Scope:            rule.Scope,
+		RequestLimit:     rule.RequestLimit,
+		RequestRemaining: rule.RequestLimit - requests,
+		TokenLimit:       rule.TokenLimit,
+		TokenRemaining:   rule.TokenLimit - tokens,
+	}
+
+	if rule.RequestLimit > 0 && requests >= rule.RequestLimit {
+		result.Allowed = false
+		result.RequestRemaining = 0
+		result.RequestReset = counter.Requests.ResetAfter(now, rule.RequestLimit)
+	}
+
+	if rule.TokenLimit > 0 && tokens >= rule.TokenLimit {
+		result.Allowed = false
+		result.TokenRemaining = 0
+		result.TokenReset = counter.Tokens.ResetAfter(now, rule.TokenLimit)
+	}
+
+	return result
+}
+
+// 扣减Token用量
+func (l *MemoryBackend) Consume(ctx context.Context, tokens int64, rules []Rule) error {
+	if tokens <= 0 {
+		return nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for _, rule := range rules {
+		l.counter(rule.Key).Tokens.Add(now, tokens)
+	}
+
+	return nil
+}
+
+// 清理过期计数器
+func (l *MemoryBackend) Cleanup() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for key, counter := range l.counters {
+		if counter.Requests.Idle(now) && counter.Tokens.Idle(now) {
+			delete(l.counters, key)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package limiter
+
+import (
+	"apiserver/config"
+	"common/logger"
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis限流后端，请求数与Token数各一个令牌桶，在脚本内原子检查与扣减，多个副本共享限额
+// 令牌桶容量为每分钟限额，按限额每分钟匀速补充，时间取Redis服务器时间，避免副本间时钟偏差
+
+// 读取令牌桶当前余量
+const bucketScript = `
+local now = redis.call('TIME')
+now = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+local window = tonumber(ARGV[1])
+
+local function load(key, limit)
+	if limit <= 0 then
+		return 0
+	end
+	local bucket = redis.call('HMGET', key, 'v', 't')
+	local level = tonumber(bucket[1])
+	if level == nil then
+		return limit
+	end
+	level = level + (now - tonumber(bucket[2])) * limit / window
+	if level > limit then
+		level = limit
+	end
+	return level
+end
+
+local function save(key, level)
+	redis.call('HSET', key, 'v', level, 't', now)
+	redis.call('PEXPIRE', key, window * 2)
+end
+
+local function reset(level, limit)
+	if limit <= 0 then
+		return 0
+	end
+	if level < 1 then
+		return math.ceil((1 - level) * window / limit)
+	end
+	return math.ceil((limit - level) * window / limit)
+end
+`
+
+// 分层检查，全部通过才占用请求
+// KEYS: 每条规则的请求桶与Token桶
+// ARGV: 窗口毫秒数，每条规则的请求限额与Token限额
+// 返回: 是否通过，超限规则序号，每条规则的请求余量、请求重置毫秒数、Token余量、Token重置毫秒数
+var allowScript = redis.NewScript(bucketScript + `
+local n = #KEYS / 2
+local levels = {}
+local denied = 0
+for i = 1, n do
+	local requestLimit = tonumber(ARGV[i * 2])
+	local tokenLimit = tonumber(ARGV[i * 2 + 1])
+	local requests = load(KEYS[i * 2 - 1], requestLimit)
+	local tokens = load(KEYS[i * 2], tokenLimit)
+	levels[i] = {requestLimit, tokenLimit, requests, tokens}
+	if denied == 0 and ((requestLimit > 0 and requests < 1) or (tokenLimit > 0 and tokens < 1)) then
+		denied = i
+	end
+end
+
+local result = {denied == 0 and 1 or 0, denied}
+for i = 1, n do
+	local requestLimit, tokenLimit, requests, tokens = unpack(levels[i])
+	if denied == 0 and requestLimit > 0 then
+		requests = requests - 1
+		save(KEYS[i * 2 - 1], requests)
+	end
+	table.insert(result, math.floor(requests))
+	table.insert(result, reset(requests, requestLimit))
+	table.insert(result, math.floor(tokens))
+	table.insert(result, reset(tokens, tokenLimit))
+end
+return result
+`)
+
+// 扣减Token，桶内最多欠下一个窗口的限额
+// KEYS: 每条规则的Token桶
+// ARGV: 窗口毫秒数，Token数，每条规则的Token限额
+var consumeScript = redis.NewScript(bucketScript + `
+local tokens = tonumber(ARGV[2])
+for i = 1, #KEYS do
+	local limit = tonumber(ARGV[i + 2])
+	if limit > 0 then
+		local level = load(KEYS[i], limit) - tokens
+		if level < -limit then
+			level = -limit
+		end
+		save(KEYS[i], level)
+	end
+end
+return 0
+`)
+
+// Redis不可用时改用进程内限流，限额只对单个副本生效，好过完全不限流
+type RedisBackend struct {
+	client   *redis.Client
+	prefix   string
+	window   time.Duration
+	fallback *MemoryBackend
+}
+
+func NewRedisBackend(cfg config.RedisConfig, window time.Duration) *RedisBackend {
+	client := redis.NewClient(&redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		DialTimeout:  cfg.Timeout,
+		ReadTimeout:  cfg.Timeout,
+		WriteTimeout: cfg.Timeout,
+	})
+	return &RedisBackend{client: client, prefix: cfg.Prefix, window: window, fallback: NewMemoryBackend(window)}
+}
+
+// 检查Redis是否可用
+func (b *RedisBackend) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
+
+func (b *RedisBackend) requestKey(key string) string {
+	return b.prefix + key + ":r"
+}
+
+func (b *RedisBackend) tokenKey(key string) string {
+	return b.prefix + key + ":t"
+}
+
+// 分层检查所有限流规则，全部通过才占用请求
+func (b *RedisBackend) Allow(ctx context.Context, rules []Rule) (*Result, error) {
+	keys := make([]string, 0, len(rules)*2)
+	args := make([]any, 0, len(rules)*2+1)
+	args = append(args, b.window.Milliseconds())
+	for _, rule := range rules {
+		keys = append(keys, b.requestKey(rule.Key), b.tokenKey(rule.Key))
+		args = append(args, rule.RequestLimit, rule.TokenLimit)
+	}
+
+	values, err := allowScript.Run(ctx, b.client, keys, args...).Int64Slice()
+	if err != nil {
+		logger.Warn("Limiter redis allow failed, fallback to memory", logger.Err(err))
+		return b.fallback.Allow(ctx, rules)
+	}
+
+	results := make([]*Result, len(rules))
+	for i, rule := range rules {
+		value := values[2+i*4 : 6+i*4]
+		results[i] = &Result{
+			Allowed:          true,
+			Scope:            rule.Scope,
+			RequestLimit:     rule.RequestLimit,
+			RequestRemaining: value[0],
+			RequestReset:     time.Duration(value[1]) * time.Millisecond,
+			TokenLimit:       rule.TokenLimit,
+			TokenRemaining:   value[2],
+			TokenReset:       time.Duration(value[3]) * time.Millisecond,
+		}
+	}
+
+	if denied := values[1]; denied > 0 {
+		result := results[denied-1]
+		result.Allowed = false
+		result.RequestRemaining = max(result.RequestRemaining, 0)
+		result.TokenRemaining = max(result.TokenRemaining, 0)
+		return result, nil
+	}
+
+	return mergeResults(results), nil
+}
+
+// 扣减Token用量
+func (b *RedisBackend) Consume(ctx context.Context, tokens int64, rules []Rule) error {
+	if tokens <= 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(rules))
+	args := make([]any, 0, len(rules)+2)
+	args = append(args, b.window.Milliseconds(), tokens)
+	for _, rule := range rules {
+		keys = append(keys, b.tokenKey(rule.Key))
+		args = append(args, rule.TokenLimit)
+	}
+
+	if err := consumeScript.Run(ctx, b.client, keys, args...).Err(); err != nil {
+		logger.Warn("Limiter redis consume failed, fallback to memory", logger.Err(err))
+		return b.fallback.Consume(ctx, tokens, rules)
+	}
+	return nil
+}
+
+// 令牌桶设置了过期时间，只需清理进程内限流的计数
+func (b *RedisBackend) Cleanup() {
+	b.fallback.Cleanup()
+}
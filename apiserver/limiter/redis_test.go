@@ -0,0 +1,178 @@
+package limiter
+
+import (
+	"apiserver/config"
+	"common/logger"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// 使用 miniredis 代替 Redis，固定服务器时间，由测试控制令牌补充与过期
+
+var testStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestMain(m *testing.M) {
+	// 降级告警只输出到控制台，避免在包目录下生成日志文件
+	logger.Init(logger.Config{Level: "error", EnableConsole: true})
+	os.Exit(m.Run())
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *RedisBackend) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(testStart)
+
+	backend := NewRedisBackend(config.RedisConfig{
+		Addr:    mr.Addr(),
+		Prefix:  "test:",
+		Timeout: 100 * time.Millisecond,
+	}, window)
+	t.Cleanup(func() { backend.client.Close() })
+
+	return mr, backend
+}
+
+func allow(t *testing.T, backend Backend, rules ...Rule) *Result {
+	t.Helper()
+	result, err := backend.Allow(context.Background(), rules)
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	return result
+}
+
+func TestRedisBurst(t *testing.T) {
+	_, backend := newTestRedis(t)
+	rule := Rule{Key: "burst", Scope: ScopeKey, RequestLimit: 3}
+
+	for i := int64(2); i >= 0; i-- {
+		result := allow(t, backend, rule)
+		if !result.Allowed || result.RequestRemaining != i {
+			t.Fatalf("request %d: allowed=%v remaining=%d", 3-i, result.Allowed, result.RequestRemaining)
+		}
+	}
+
+	result := allow(t, backend, rule)
+	if result.Allowed || result.Scope != ScopeKey {
+		t.Fatalf("burst exceeded: allowed=%v scope=%s", result.Allowed, result.Scope)
+	}
+	if wait := result.RetryAfter(); wait <= 0 || wait > 20*time.Second {
+		t.Fatalf("retry after %v, want one token interval", wait)
+	}
+}
+
+func TestRedisRefill(t *testing.T) {
+	mr, backend := newTestRedis(t)
+	rule := Rule{Key: "refill", Scope: ScopeKey, RequestLimit: 3}
+
+	for range 3 {
+		allow(t, backend, rule)
+	}
+	if allow(t, backend, rule).Allowed {
+		t.Fatal("bucket should be empty")
+	}
+
+	// 每分钟3个，20秒补充一个
+	mr.SetTime(testStart.Add(20 * time.Second))
+	if !allow(t, backend, rule).Allowed {
+		t.Fatal("one token should be refilled")
+	}
+	if allow(t, backend, rule).Allowed {
+		t.Fatal("only one token should be refilled")
+	}
+
+	// 补充不超过容量
+	mr.SetTime(testStart.Add(10 * time.Minute))
+	if result := allow(t, backend, rule); result.RequestRemaining != 2 {
+		t.Fatalf("remaining %d after full refill, want 2", result.RequestRemaining)
+	}
+}
+
+func TestRedisConsumeCost(t *testing.T) {
+	mr, backend := newTestRedis(t)
+	ctx := context.Background()
+	rule := Rule{Key: "cost", Scope: ScopeWorkspace, TokenLimit: 100}
+
+	if err := backend.Consume(ctx, 60, []Rule{rule}); err != nil {
+		t.Fatal(err)
+	}
+	if result := allow(t, backend, rule); !result.Allowed || result.TokenRemaining != 40 {
+		t.Fatalf("allowed=%v tokens=%d, want 40", result.Allowed, result.TokenRemaining)
+	}
+
+	// 超出余量时允许欠下，欠额最多一个窗口的限额
+	if err := backend.Consume(ctx, 1000, []Rule{rule}); err != nil {
+		t.Fatal(err)
+	}
+	result := allow(t, backend, rule)
+	if result.Allowed || result.TokenRemaining != 0 {
+		t.Fatalf("allowed=%v tokens=%d, want denied", result.Allowed, result.TokenRemaining)
+	}
+
+	// 欠额为-100，一个窗口后恢复到0，仍需再补充一个
+	mr.SetTime(testStart.Add(window))
+	if allow(t, backend, rule).Allowed {
+		t.Fatal("debt should be capped at one window, not cleared")
+	}
+	mr.SetTime(testStart.Add(window + time.Second))
+	if !allow(t, backend, rule).Allowed {
+		t.Fatal("tokens should be refilled after the debt is repaid")
+	}
+}
+
+func TestRedisLayeredDenyDoesNotConsume(t *testing.T) {
+	_, backend := newTestRedis(t)
+	user := Rule{Key: "user", Scope: ScopeUser, RequestLimit: 10}
+	key := Rule{Key: "key", Scope: ScopeKey, RequestLimit: 1}
+
+	allow(t, backend, user, key)
+	result := allow(t, backend, user, key)
+	if result.Allowed || result.Scope != ScopeKey {
+		t.Fatalf("allowed=%v scope=%s, want denied by key", result.Allowed, result.Scope)
+	}
+
+	// 被拒绝的请求不占用其他层级的额度
+	if result := allow(t, backend, user); result.RequestRemaining != 8 {
+		t.Fatalf("user remaining %d, want 8", result.RequestRemaining)
+	}
+}
+
+func TestRedisKeyExpiry(t *testing.T) {
+	mr, backend := newTestRedis(t)
+	rule := Rule{Key: "expire", Scope: ScopeKey, RequestLimit: 3, TokenLimit: 100}
+
+	allow(t, backend, rule)
+	requestKey := backend.requestKey(rule.Key)
+	if ttl := mr.TTL(requestKey); ttl != 2*window {
+		t.Fatalf("ttl %v, want %v", ttl, 2*window)
+	}
+	if mr.Exists(backend.tokenKey(rule.Key)) {
+		t.Fatal("token bucket should not be written by allow")
+	}
+
+	mr.FastForward(2*window + time.Millisecond)
+	if mr.Exists(requestKey) {
+		t.Fatal("request bucket should expire")
+	}
+}
+
+func TestRedisFallbackToMemory(t *testing.T) {
+	mr, backend := newTestRedis(t)
+	mr.Close()
+
+	rule := Rule{Key: "fallback", Scope: ScopeKey, RequestLimit: 1, TokenLimit: 100}
+	if result := allow(t, backend, rule); !result.Allowed {
+		t.Fatal("first request should be allowed by memory backend")
+	}
+	if result := allow(t, backend, rule); result.Allowed {
+		t.Fatal("memory backend should still enforce the limit")
+	}
+
+	if err := backend.Consume(context.Background(), 10, []Rule{rule}); err != nil {
+		t.Fatalf("consume should fall back to memory: %v", err)
+	}
+}
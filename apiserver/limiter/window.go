@@ -0,0 +1,81 @@
+package limiter
+
+import "time"
+
+// 滑动窗口计数器
+// 以当前窗口计数加上前一窗口按剩余比例折算的计数作为窗口内的用量
+
+type Window struct {
+	Size     time.Duration // 窗口大小
+	start    time.Time     // 当前窗口起始时间
+	current  int64         // 当前窗口计数
+	previous int64         // 前一窗口计数
+}
+
+func NewWindow(size time.Duration) *Window {
+	return &Window{Size: size}
+}
+
+// 窗口滑动到指定时间
+func (w *Window) advance(now time.Time) {
+	if w.start.IsZero() {
+		w.start = now.Truncate(w.Size)
+		return
+	}
+
+	elapsed := now.Sub(w.start)
+	if elapsed < w.Size {
+		return
+	}
+
+	if elapsed < 2*w.Size {
+		w.previous = w.current
+	} else {
+		w.previous = 0
+	}
+
+	w.current = 0
+	w.start = now.Truncate(w.Size)
+}
+
+// 窗口内用量
+func (w *Window) Count(now time.Time) int64 {
+	w.advance(now)
+	remain := w.Size - now.Sub(w.start)
+	weighted := float64(w.previous) * float64(remain) / float64(w.Size)
+	return w.current + int64(weighted)
+}
+
+// 增加用量
+func (w *Window) Add(now time.Time, n int64) {
+	w.advance(now)
+	w.current += n
+}
+
+// 用量降至限额以下所需的时间
+func (w *Window) ResetAfter(now time.Time, limit int64) time.Duration {
+	w.advance(now)
+	if w.Count(now) < limit {
+		return 0
+	}
+
+	// 当前窗口已经超限，只能等待下一窗口
+	if w.current >= limit || w.previous == 0 {
+		return w.Size - now.Sub(w.start)
+	}
+
+	// 前一窗口的折算用量随时间线性下降
+	allowed := float64(limit - w.current)
+	remain := time.Duration(allowed * float64(w.Size) / float64(w.previous))
+	wait := w.Size - now.Sub(w.start) - remain
+	if wait < time.Millisecond {
+		wait = time.Millisecond
+	}
+
+	return wait
+}
+
+// 窗口是否已过期
+func (w *Window) Idle(now time.Time) bool {
+	return now.Sub(w.start) >= 2*w.Size
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"apiserver/config"
+	"apiserver/limiter"
+	"apiserver/metrics"
 	"apiserver/middleware"
 	"apiserver/model"
 	"apiserver/proxy"
 	"apiserver/rest"
+	"apiserver/snapshot"
+	"apiserver/user"
+	"apiserver/watch"
 	"common/logger"
 	"context"
 	"flag"
@@ -24,6 +29,8 @@ func main() {
 	configFileName := flag.String("config", "config/config.yaml", "config from file")
 	host := flag.String("host", "", "listen ip")
 	port := flag.Int("port", 8000, "listen port")
+	metricsHost := flag.String("metrics-host", "", "metrics listen ip")
+	metricsPort := flag.Int("metrics-port", 9090, "metrics listen port, only expose on the internal network, 0 disables")
 	flag.Parse()
 
 	// 初始化配置
@@ -40,12 +47,41 @@ func main() {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// 初始化密钥缓存
+	user.Init()
+
+	// 恢复上次保存的快照，开放平台不可达时继续服务
+	snapshot.Load()
+
 	// 加载模型服务
 	go model.LoadServicesTask(ctx)
 
+	// 定时保存快照
+	go snapshot.SaveTask(ctx)
+
+	// 监听变更事件
+	go watch.WatchTask(ctx)
+
+	// 探测模型服务健康状态
+	go model.HealthCheckTask(ctx)
+
+	// 初始化限流后端
+	limiter.Init(ctx)
+
+	// 清理限流计数
+	go limiter.CleanupTask(ctx)
+
+	// 上报使用量
+	go user.ReportUsageLogTask(ctx)
+
 	// 启动HTTP服务
 	go RunServer(*host, *port)
 
+	// 指标单独监听，不对外暴露上游地址
+	if *metricsPort > 0 {
+		go RunMetricsServer(*metricsHost, *metricsPort)
+	}
+
 	// 监听系统信号（SIGINT, SIGTERM）
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -55,13 +91,32 @@ func main() {
 	cancel()
 	time.Sleep(time.Second)
 
+	// 保存最新快照
+	if err := snapshot.Save(); err != nil {
+		logger.Error("Save snapshot", logger.Err(err))
+	}
+
+	// 上报剩余使用量
+	reportCtx, reportCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer reportCancel()
+	if err := user.ReportUsageLog(reportCtx); err != nil {
+		logger.Error("ReportUsageLog", logger.Err(err))
+	}
+
 	logger.Info("Application stoped")
 }
 
 func RunServer(host string, port int) {
 
 	r := gin.New()
-	r.Use(middleware.GinLogger(), middleware.GinRecovery())
+
+	// 来源地址只信任配置的代理，密钥的地址限制和认证失败限流依赖真实来源地址
+	if err := r.SetTrustedProxies(config.GetServer().TrustedProxies); err != nil {
+		logger.Error("SetTrustedProxies", logger.Err(err))
+		return
+	}
+
+	r.Use(middleware.GinLogger(), middleware.GinRecovery(), metrics.Middleware())
 
 	// 分组路由
 	SetRouter(r)
@@ -75,6 +130,18 @@ func RunServer(host string, port int) {
 
 }
 
+func RunMetricsServer(host string, port int) {
+
+	r := gin.New()
+	r.Use(middleware.GinRecovery())
+	r.GET("/metrics", metrics.Handler())
+
+	serverAddress := fmt.Sprintf("%s:%d", host, port)
+	if err := r.Run(serverAddress); err != nil {
+		logger.Error("RunMetricsServer", logger.Err(err))
+	}
+}
+
 func SetRouter(r *gin.Engine) {
 	r.GET("/health", rest.NewHealthHandler())
 
@@ -83,6 +150,10 @@ func SetRouter(r *gin.Engine) {
 
 func SetProxyRouter(r *gin.Engine) {
 
+	r.GET("/v1/models", proxy.NewModelListHandler())
+	r.GET("/v1/models/*id", proxy.NewModelRetrieveHandler())
+	r.GET("/v1/usage/summary", proxy.NewUsageSummaryHandler())
+
 	r.POST("/tokenize", proxy.NewDefaultHandler())
 	r.POST("/classify", proxy.NewClassifyHandler())
 
@@ -90,9 +161,9 @@ func SetProxyRouter(r *gin.Engine) {
 	r.POST("/v1/embeddings", proxy.NewEmbeddingsHandler())
 	r.POST("/v1/rerank", proxy.NewRerankHandler())
 
-	r.POST("/v1/audio/transcriptions", proxy.NewDefaultHandler())
-	r.POST("/v1/audio/translations", proxy.NewDefaultHandler())
-	r.POST("/v1/audio/speech", proxy.NewDefaultHandler())
+	r.POST("/v1/audio/transcriptions", proxy.NewTranscriptionsHandler())
+	r.POST("/v1/audio/translations", proxy.NewTranscriptionsHandler())
+	r.POST("/v1/audio/speech", proxy.NewSpeechHandler())
 
-	r.POST("/v1/images/generations", proxy.NewDefaultHandler())
+	r.POST("/v1/images/generations", proxy.NewImagesHandler())
 }
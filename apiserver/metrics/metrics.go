@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "apiserver"
+
+// 请求耗时分布，推理请求耗时较长
+var latencyBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// 流式输出间隔分布
+var tokenBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method, model and status.",
+	}, []string{"route", "method", "model", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method, model and status.",
+		Buckets:   latencyBuckets,
+	}, []string{"route", "method", "model", "status"})
+
+	httpInflight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_inflight_requests",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Requests forwarded to inference targets by model, target and status.",
+	}, []string{"model", "target", "status"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_response_seconds",
+		Help:      "Time until inference targets return response headers.",
+		Buckets:   latencyBuckets,
+	}, []string{"model", "target"})
+
+	upstreamInflight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_inflight_requests",
+		Help:      "Requests currently being processed by inference targets.",
+	}, []string{"service", "target"})
+
+	shadowRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shadow_requests_total",
+		Help:      "Requests mirrored to shadow targets by model, target and status.",
+	}, []string{"model", "target", "status"})
+
+	tokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_total",
+		Help:      "Tokens consumed by model and type.",
+	}, []string{"model", "type"})
+
+	timeToFirstToken = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "time_to_first_token_seconds",
+		Help:      "Time from request start to the first streamed chunk.",
+		Buckets:   latencyBuckets,
+	}, []string{"model"})
+
+	interTokenLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "inter_token_latency_seconds",
+		Help:      "Time between consecutive streamed chunks.",
+		Buckets:   tokenBuckets,
+	}, []string{"model"})
+
+	queueWaiting = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_waiting_requests",
+		Help:      "Requests waiting for a concurrency slot by model.",
+	}, []string{"model"})
+
+	queueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "queue_wait_seconds",
+		Help:      "Time requests waited for a concurrency slot.",
+		Buckets:   latencyBuckets,
+	}, []string{"model"})
+
+	queueRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_rejected_total",
+		Help:      "Requests rejected by the concurrency queue by model and reason.",
+	}, []string{"model", "reason"})
+
+	keyCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_key_cache_total",
+		Help:      "API key cache lookups by result.",
+	}, []string{"result"})
+
+	authThrottled = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_throttled_total",
+		Help:      "Requests rejected because the client IP exceeded the auth failure limit.",
+	})
+)
+
+// 上下文中记录模型名称的键
+const ModelKey = "metrics.model"
+
+// 记录HTTP请求指标
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpInflight.Inc()
+
+		c.Next()
+
+		httpInflight.Dec()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := strconv.Itoa(c.Writer.Status())
+		modelName := c.GetString(ModelKey)
+
+		httpRequests.WithLabelValues(route, c.Request.Method, modelName, status).Inc()
+		httpDuration.WithLabelValues(route, c.Request.Method, modelName, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// 指标接口
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// 转发结果，连接失败时状态码为0
+func ObserveUpstream(modelName, target string, statusCode int, duration time.Duration) {
+	status := "error"
+	if statusCode > 0 {
+		status = strconv.Itoa(statusCode)
+	}
+
+	upstreamRequests.WithLabelValues(modelName, target, status).Inc()
+	if statusCode > 0 {
+		upstreamDuration.WithLabelValues(modelName, target).Observe(duration.Seconds())
+	}
+}
+
+func AddUpstreamInflight(serviceID, target string, delta float64) {
+	upstreamInflight.WithLabelValues(serviceID, target).Add(delta)
+}
+
+// 目标移除后删除处理中请求数
+func DeleteUpstreamInflight(serviceID, target string) {
+	upstreamInflight.DeleteLabelValues(serviceID, target)
+}
+
+// 镜像请求结果，连接失败时状态码为0，超出并发上限时目标为空
+func ObserveShadow(modelName, target string, statusCode int) {
+	status := "error"
+	if target == "" {
+		status = "dropped"
+	} else if statusCode > 0 {
+		status = strconv.Itoa(statusCode)
+	}
+
+	shadowRequests.WithLabelValues(modelName, target, status).Inc()
+}
+
+func AddTokens(modelName string, inputTokens, outputTokens int) {
+	if inputTokens > 0 {
+		tokens.WithLabelValues(modelName, "input").Add(float64(inputTokens))
+	}
+	if outputTokens > 0 {
+		tokens.WithLabelValues(modelName, "output").Add(float64(outputTokens))
+	}
+}
+
+func ObserveFirstToken(modelName string, duration time.Duration) {
+	timeToFirstToken.WithLabelValues(modelName).Observe(duration.Seconds())
+}
+
+func ObserveInterToken(modelName string, duration time.Duration) {
+	interTokenLatency.WithLabelValues(modelName).Observe(duration.Seconds())
+}
+
+func AddQueueWaiting(modelName string, delta float64) {
+	queueWaiting.WithLabelValues(modelName).Add(delta)
+}
+
+func ObserveQueueWait(modelName string, duration time.Duration) {
+	queueWait.WithLabelValues(modelName).Observe(duration.Seconds())
+}
+
+func QueueRejected(modelName, reason string) {
+	queueRejected.WithLabelValues(modelName, reason).Inc()
+}
+
+func KeyCacheHit() {
+	keyCache.WithLabelValues("hit").Inc()
+}
+
+func KeyCacheMiss() {
+	keyCache.WithLabelValues("miss").Inc()
+}
+
+// 命中无效密钥缓存
+func KeyCacheNegative() {
+	keyCache.WithLabelValues("negative").Inc()
+}
+
+// 缓存过期后返回旧数据
+func KeyCacheStale() {
+	keyCache.WithLabelValues("stale").Inc()
+}
+
+// 开放平台不可达时使用快照
+func KeyCacheSnapshot() {
+	keyCache.WithLabelValues("snapshot").Inc()
+}
+
+func AuthThrottled() {
+	authThrottled.Inc()
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"apiserver/config"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// 负载均衡接口
+type Balancer interface {
+	Strategy() string
+	Select(targets []*Target, hashKey string) *Target
+}
+
+func NewBalancer(strategy string) Balancer {
+	switch strategy {
+	case config.BalanceWeighted:
+		return &WeightedBalancer{currents: make(map[*Target]int64)}
+	case config.BalanceLeastInflight:
+		return &LeastInflightBalancer{}
+	case config.BalanceP2C:
+		return &P2CBalancer{}
+	case config.BalanceHash:
+		return &HashBalancer{}
+	default:
+		return &RoundRobinBalancer{}
+	}
+}
+
+// 轮询
+type RoundRobinBalancer struct {
+	index atomic.Uint64
+}
+
+func (b *RoundRobinBalancer) Strategy() string {
+	return config.BalanceRoundRobin
+}
+
+func (b *RoundRobinBalancer) Select(targets []*Target, hashKey string) *Target {
+	index := b.index.Add(1)
+	return targets[index%uint64(len(targets))]
+}
+
+// 平滑加权轮询
+type WeightedBalancer struct {
+	mutex    sync.Mutex
+	currents map[*Target]int64
+}
+
+func (b *WeightedBalancer) Strategy() string {
+	return config.BalanceWeighted
+}
+
+func (b *WeightedBalancer) Select(targets []*Target, hashKey string) *Target {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var total int64
+	var best *Target
+	currents := make(map[*Target]int64, len(targets))
+	for _, target := range targets {
+		weight := int64(target.Weight)
+		total += weight
+		currents[target] = b.currents[target] + weight
+		if best == nil || currents[target] > currents[best] {
+			best = target
+		}
+	}
+
+	currents[best] -= total
+	b.currents = currents
+
+	return best
+}
+
+// 最少处理中请求，按权重折算
+type LeastInflightBalancer struct {
+	index atomic.Uint64
+}
+
+func (b *LeastInflightBalancer) Strategy() string {
+	return config.BalanceLeastInflight
+}
+
+func (b *LeastInflightBalancer) Select(targets []*Target, hashKey string) *Target {
+	// 从轮询位置开始比较，负载相同时分散到不同目标
+	start := int(b.index.Add(1) % uint64(len(targets)))
+	best := targets[start]
+	for i := 1; i < len(targets); i++ {
+		target := targets[(start+i)%len(targets)]
+		if loadScore(target) < loadScore(best) {
+			best = target
+		}
+	}
+	return best
+}
+
+// 随机两选一
+type P2CBalancer struct{}
+
+func (b *P2CBalancer) Strategy() string {
+	return config.BalanceP2C
+}
+
+func (b *P2CBalancer) Select(targets []*Target, hashKey string) *Target {
+	if len(targets) == 1 {
+		return targets[0]
+	}
+
+	i := rand.IntN(len(targets))
+	j := rand.IntN(len(targets) - 1)
+	if j >= i {
+		j++
+	}
+
+	if loadScore(targets[j]) < loadScore(targets[i]) {
+		return targets[j]
+	}
+	return targets[i]
+}
+
+// 会话一致性哈希，相同会话尽量转发到同一目标以复用KV缓存
+type HashBalancer struct {
+	fallback RoundRobinBalancer
+}
+
+func (b *HashBalancer) Strategy() string {
+	return config.BalanceHash
+}
+
+func (b *HashBalancer) Select(targets []*Target, hashKey string) *Target {
+	if hashKey == "" {
+		return b.fallback.Select(targets, hashKey)
+	}
+
+	// 加权最高随机权重哈希，目标增减时只影响其上的会话
+	var best *Target
+	bestScore := math.Inf(-1)
+	for _, target := range targets {
+		h := fnv.New64a()
+		h.Write([]byte(hashKey))
+		h.Write([]byte{0})
+		h.Write([]byte(target.ServiceID))
+		h.Write([]byte(target.Key()))
+
+		unit := (float64(mix64(h.Sum64())>>11) + 0.5) / float64(1<<53)
+		score := float64(target.Weight) / -math.Log(unit)
+		if score > bestScore {
+			best = target
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// 负载评分，越小越空闲
+func loadScore(target *Target) float64 {
+	return float64(target.Inflight()+1) / float64(max(target.Weight, 1))
+}
+
+// 哈希值混淆，改善分布均匀性
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"apiserver/config"
+	"common/logger"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 转发目标健康状态
+// 主动探测连续失败时标记为不可用，探测成功后恢复；
+// 转发连续失败时摘除一段时间，到期后重新接入，再次被摘除时摘除时长翻倍
+type Health struct {
+	mutex         sync.Mutex
+	probeFailures int       // 连续探测失败次数
+	probeDown     bool      // 探测判定不可用
+	failures      int       // 连续转发失败次数
+	ejections     int       // 连续摘除次数
+	ejectedUntil  time.Time // 摘除截止时间
+}
+
+func (h *Health) Healthy(now time.Time) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return !h.probeDown && !now.Before(h.ejectedUntil)
+}
+
+// 转发成功
+func (h *Health) ReportSuccess() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.failures = 0
+	if time.Now().After(h.ejectedUntil) {
+		h.ejections = 0
+	}
+}
+
+// 转发失败，返回是否被摘除
+func (h *Health) ReportFailure() bool {
+	cfg := config.GetHealth()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := time.Now()
+	if now.Before(h.ejectedUntil) {
+		return false
+	}
+
+	h.failures++
+	if h.failures < cfg.FailureThreshold {
+		return false
+	}
+
+	ejection := cfg.BaseEjection << min(h.ejections, 16)
+	if ejection <= 0 || ejection > cfg.MaxEjection {
+		ejection = cfg.MaxEjection
+	}
+
+	h.ejections++
+	h.failures = 0
+	h.ejectedUntil = now.Add(ejection)
+
+	return true
+}
+
+// 记录探测结果，返回状态是否变化
+func (h *Health) ReportProbe(ok bool) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if ok {
+		h.probeFailures = 0
+		changed := h.probeDown
+		h.probeDown = false
+		return changed
+	}
+
+	h.probeFailures++
+	if !h.probeDown && h.probeFailures >= config.GetHealth().FailureThreshold {
+		h.probeDown = true
+		return true
+	}
+
+	return false
+}
+
+// 所有转发目标
+func (m *Manager) Targets() []*Target {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var targets []*Target
+	for _, services := range m.modes {
+		targets = append(targets, services.targets...)
+	}
+	return targets
+}
+
+// 健康探测任务
+func HealthCheckTask(ctx context.Context) {
+
+	logger.Info("Health background task start")
+
+	ticker := time.NewTicker(config.GetHealth().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			CheckTargets(ctx)
+		case <-ctx.Done():
+			goto end
+		}
+	}
+
+end:
+	logger.Info("Health background task final")
+}
+
+// 并发探测所有转发目标
+func CheckTargets(ctx context.Context) {
+	cfg := config.GetHealth()
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	var wg sync.WaitGroup
+	for _, target := range manager.Targets() {
+		wg.Add(1)
+		go func(target *Target) {
+			defer wg.Done()
+
+			ok := probe(ctx, client, target.URL()+cfg.Path)
+			if target.Health.ReportProbe(ok) {
+				logger.Warn("Target health changed",
+					logger.String("service", target.ServiceID),
+					logger.String("target", target.Key()),
+					logger.Any("healthy", ok))
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+// 连接失败或返回5xx时判定不可用，未实现探测路径的推理服务返回404仍视为存活
+func probe(ctx context.Context, client *http.Client, url string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode < http.StatusInternalServerError
+}
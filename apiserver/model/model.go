@@ -2,8 +2,10 @@ package model
 
 import (
 	"apiserver/client/openserver"
+	"apiserver/config"
 	"common/logger"
 	"context"
+	"sort"
 	"sync"
 	"time"
 )
@@ -11,9 +13,27 @@ import (
 // 模型名称对应模型服务列表
 type Models map[string]*Services
 
+// 模型名称对应模型元数据
+type ModelInfos map[string]*openserver.ModelInfoResponse
+
+// 别名对应实际模型
+type ModelAliases map[string]*openserver.ModelAliasResponse
+
+// 模型名称对应流量分配规则
+type ModelSplits map[string]*Split
+
 type Manager struct {
 	mutex sync.Mutex
 	modes Models
+	infos ModelInfos
+	alias ModelAliases
+	split ModelSplits
+
+	// 最近一次成功加载的原始数据，用于保存快照
+	lastServices []openserver.ModelServicesResponse
+	lastInfos    []openserver.ModelInfoResponse
+	lastAliases  []openserver.ModelAliasResponse
+	lastSplits   []openserver.ModelSplitResponse
 }
 
 var (
@@ -21,16 +41,140 @@ var (
 )
 
 func init() {
-	manager = Manager{modes: make(Models)}
+	manager = Manager{modes: make(Models), infos: make(ModelInfos), alias: make(ModelAliases), split: make(ModelSplits)}
 }
 
 func (m *Manager) Refresh(models Models) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+
+	for modelName, services := range models {
+		old := m.modes[modelName]
+		services.Inherit(old)
+
+		// 策略未变化时沿用均衡器状态
+		strategy := config.GetBalance().GetStrategy(modelName)
+		if old != nil && old.Balancer.Strategy() == strategy {
+			services.Balancer = old.Balancer
+		} else {
+			services.Balancer = NewBalancer(strategy)
+		}
+
+		// 每个服务一个并发队列，沿用同一服务的队列，保留执行中和排队中的请求
+		for _, service := range services.Services {
+			if found := old.FindService(service.ID); found != nil {
+				service.Queue = found.Queue
+			} else {
+				service.Queue = NewQueue(modelName)
+			}
+			service.Queue.SetLimit(service.MaxInflight)
+
+			for _, target := range service.Targets {
+				target.Queue = service.Queue
+			}
+		}
+	}
+
+	retireTargets(m.modes, models)
 	m.modes = models
 }
 
-func (m *Manager) SelectTarget(modelName string) *openserver.ServiceTarget {
+// 移除不再使用的目标的指标
+func retireTargets(old, current Models) {
+	kept := make(map[string]bool)
+	for _, services := range current {
+		for _, target := range services.targets {
+			kept[target.ServiceID+"/"+target.Key()] = true
+		}
+	}
+
+	for _, services := range old {
+		for _, target := range services.targets {
+			if !kept[target.ServiceID+"/"+target.Key()] {
+				target.retire()
+			}
+		}
+	}
+}
+
+func (m *Manager) RefreshInfos(infos ModelInfos) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.infos = infos
+}
+
+func (m *Manager) RefreshAliases(aliases ModelAliases) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.alias = aliases
+}
+
+func (m *Manager) RefreshSplits(splits ModelSplits) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for modelName, split := range splits {
+		split.Inherit(m.split[modelName])
+	}
+
+	m.split = splits
+}
+
+// 已部署的模型名称
+func (m *Manager) ModelNames() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	names := make([]string, 0, len(m.modes))
+	for name, services := range m.modes {
+		if len(services.Services) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Manager) FindInfo(modelName string) *openserver.ModelInfoResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.infos[modelName]
+}
+
+// 解析别名，名称不是别名或实际模型未部署时返回空
+func (m *Manager) ResolveAlias(name string) *openserver.ModelAliasResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.modes[name]; ok {
+		return nil
+	}
+
+	found := m.alias[name]
+	if found == nil {
+		return nil
+	}
+
+	if services := m.modes[found.ModelName]; services == nil || len(services.Services) == 0 {
+		return nil
+	}
+
+	return found
+}
+
+// 实际模型已部署的别名，按别名排序
+func (m *Manager) Aliases() []*openserver.ModelAliasResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	aliases := make([]*openserver.ModelAliasResponse, 0, len(m.alias))
+	for _, alias := range m.alias {
+		if services := m.modes[alias.ModelName]; services != nil && len(services.Services) > 0 {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+	return aliases
+}
+
+func (m *Manager) SelectTarget(modelName, hashKey, splitKey string, exclude ...*Target) *Target {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	found := m.modes[modelName]
@@ -38,12 +182,60 @@ func (m *Manager) SelectTarget(modelName string) *openserver.ServiceTarget {
 		return nil
 	}
 
-	return found.SelectTarget()
+	return found.SelectTarget(m.split[modelName], hashKey, splitKey, exclude...)
+}
+
+func (m *Manager) SelectShadow(modelName string) *Target {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	found := m.modes[modelName]
+	split := m.split[modelName]
+	if found == nil || split == nil || !split.Mirror() {
+		return nil
+	}
+
+	return found.SelectShadow(split)
+}
+
+// 已部署的模型名称
+func ModelNames() []string {
+	return manager.ModelNames()
+}
+
+// 查询模型元数据，可能为空
+func FindInfo(modelName string) *openserver.ModelInfoResponse {
+	return manager.FindInfo(modelName)
+}
+
+// 解析模型别名，不是别名时为空
+func ResolveAlias(name string) *openserver.ModelAliasResponse {
+	return manager.ResolveAlias(name)
 }
 
-// 选择转发模型
-func SelectTarget(modelName string) *openserver.ServiceTarget {
-	return manager.SelectTarget(modelName)
+// 实际模型已部署的别名
+func Aliases() []*openserver.ModelAliasResponse {
+	return manager.Aliases()
+}
+
+// 选择转发目标，哈希键用于会话保持，分配键用于流量分配保持，排除已尝试过的目标
+func SelectTarget(modelName, hashKey, splitKey string, exclude ...*Target) *Target {
+	return manager.SelectTarget(modelName, hashKey, splitKey, exclude...)
+}
+
+// 按镜像比例选择镜像目标，不镜像本次请求时为空
+func SelectShadow(modelName string) *Target {
+	return manager.SelectShadow(modelName)
+}
+
+// 立即重新加载模型服务的请求
+var reloadRequest = make(chan struct{}, 1)
+
+// 请求立即重新加载模型服务，已有请求未处理时合并
+func RequestReload() {
+	select {
+	case reloadRequest <- struct{}{}:
+	default:
+	}
 }
 
 // 加载模型服务任务
@@ -60,6 +252,8 @@ func LoadServicesTask(ctx context.Context) {
 		select {
 		case <-ticker.C:
 			LoadServices(ctx)
+		case <-reloadRequest:
+			LoadServices(ctx)
 		case <-ctx.Done():
 			goto end
 		}
@@ -79,14 +273,67 @@ func LoadServices(ctx context.Context) {
 		return
 	}
 
+	applyServices(resp)
+
+	// 别名加载失败时保留旧数据，避免调用方的别名突然失效
+	aliases, err := openserver.FindModelAliases(ctx)
+	if err != nil {
+		logger.Error("FindModelAliases", logger.Err(err))
+	} else {
+		applyAliases(aliases)
+	}
+
+	// 流量分配规则加载失败时保留旧数据
+	splits, err := openserver.FindModelSplits(ctx)
+	if err != nil {
+		logger.Error("FindModelSplits", logger.Err(err))
+	} else {
+		applySplits(splits)
+	}
+
+	// 模型元数据仅用于展示，加载失败时保留旧数据
+	infos, err := openserver.FindModelInfos(ctx)
+	if err != nil {
+		logger.Error("FindModelInfos", logger.Err(err))
+		return
+	}
+
+	applyInfos(infos)
+}
+
+// 最近一次成功加载的模型数据
+type SnapshotData struct {
+	Services []openserver.ModelServicesResponse
+	Infos    []openserver.ModelInfoResponse
+	Aliases  []openserver.ModelAliasResponse
+	Splits   []openserver.ModelSplitResponse
+}
+
+// 恢复快照中的模型数据，开放平台不可达时使用
+func Restore(data *SnapshotData) {
+	applyServices(data.Services)
+	applyInfos(data.Infos)
+	applyAliases(data.Aliases)
+	applySplits(data.Splits)
+}
+
+// 最近一次成功加载的模型数据
+func Snapshot() *SnapshotData {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return &SnapshotData{
+		Services: manager.lastServices,
+		Infos:    manager.lastInfos,
+		Aliases:  manager.lastAliases,
+		Splits:   manager.lastSplits,
+	}
+}
+
+func applyServices(resp []openserver.ModelServicesResponse) {
 	models := make(Models)
-	for _, s := range resp {
-		service := &Service{
-			ID:      s.ID,
-			Power:   s.Power,
-			Load:    s.Load,
-			Targets: s.Targets,
-		}
+	for i := range resp {
+		s := &resp[i]
+		service := NewService(s)
 
 		found := models[s.ModelName]
 		if found == nil {
@@ -94,9 +341,51 @@ func LoadServices(ctx context.Context) {
 			models[s.ModelName] = found
 		}
 
-		found.Services = append(found.Services, service)
-
+		found.Add(service)
 	}
 
 	manager.Refresh(models)
+
+	manager.mutex.Lock()
+	manager.lastServices = resp
+	manager.mutex.Unlock()
+}
+
+func applyInfos(infos []openserver.ModelInfoResponse) {
+	modelInfos := make(ModelInfos)
+	for i := range infos {
+		modelInfos[infos[i].Name] = &infos[i]
+	}
+
+	manager.RefreshInfos(modelInfos)
+
+	manager.mutex.Lock()
+	manager.lastInfos = infos
+	manager.mutex.Unlock()
+}
+
+func applyAliases(aliases []openserver.ModelAliasResponse) {
+	modelAliases := make(ModelAliases)
+	for i := range aliases {
+		modelAliases[aliases[i].Alias] = &aliases[i]
+	}
+
+	manager.RefreshAliases(modelAliases)
+
+	manager.mutex.Lock()
+	manager.lastAliases = aliases
+	manager.mutex.Unlock()
+}
+
+func applySplits(splits []openserver.ModelSplitResponse) {
+	modelSplits := make(ModelSplits)
+	for i := range splits {
+		modelSplits[splits[i].ModelName] = NewSplit(&splits[i])
+	}
+
+	manager.RefreshSplits(modelSplits)
+
+	manager.mutex.Lock()
+	manager.lastSplits = splits
+	manager.mutex.Unlock()
 }
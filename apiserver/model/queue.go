@@ -0,0 +1,149 @@
+package model
+
+import (
+	"apiserver/metrics"
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 服务并发队列，达到并发上限后按优先级排队，同优先级先进先出
+
+// 排队优先级数量，对应工作空间服务等级
+const QueuePriorities = 3
+
+var (
+	ErrQueueFull    = errors.New("queue is full")
+	ErrQueueTimeout = errors.New("queue timeout")
+)
+
+type queueWaiter struct {
+	ready   chan struct{} // 获得执行名额时关闭
+	granted bool
+}
+
+type Queue struct {
+	mutex    sync.Mutex
+	name     string // 模型名称，用于指标
+	limit    int64  // 并发上限，小于等于0表示不限制
+	inflight int64  // 执行中的请求数
+	waiting  int    // 排队中的请求数
+	waiters  [QueuePriorities]*list.List
+}
+
+func NewQueue(name string) *Queue {
+	q := &Queue{name: name}
+	for i := range q.waiters {
+		q.waiters[i] = list.New()
+	}
+	return q
+}
+
+// 更新并发上限，上限提高时唤醒排队的请求
+func (q *Queue) SetLimit(limit int64) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.limit = limit
+	q.dispatch()
+}
+
+func (q *Queue) idle() bool {
+	return q.limit <= 0 || q.inflight < q.limit
+}
+
+// 有空闲名额时按优先级唤醒排队的请求
+func (q *Queue) dispatch() {
+	for q.waiting > 0 && q.idle() {
+		w := q.pop()
+		w.granted = true
+		q.inflight++
+		close(w.ready)
+	}
+}
+
+func (q *Queue) pop() *queueWaiter {
+	for i := QueuePriorities - 1; i >= 0; i-- {
+		if e := q.waiters[i].Front(); e != nil {
+			q.waiters[i].Remove(e)
+			q.waiting--
+			metrics.AddQueueWaiting(q.name, -1)
+			return e.Value.(*queueWaiter)
+		}
+	}
+	return nil
+}
+
+// 获取执行名额，队列已满、排队超时或请求取消时返回错误，成功时返回排队时长
+func (q *Queue) Acquire(ctx context.Context, priority, size int, timeout time.Duration) (time.Duration, error) {
+	q.mutex.Lock()
+	if q.waiting == 0 && q.idle() {
+		q.inflight++
+		q.mutex.Unlock()
+		return 0, nil
+	}
+
+	if q.waiting >= size {
+		q.mutex.Unlock()
+		return 0, ErrQueueFull
+	}
+
+	priority = min(max(priority, 0), QueuePriorities-1)
+	w := &queueWaiter{ready: make(chan struct{})}
+	e := q.waiters[priority].PushBack(w)
+	q.waiting++
+	metrics.AddQueueWaiting(q.name, 1)
+	q.mutex.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return time.Since(start), nil
+	case <-timer.C:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	// 超时的同时获得了名额，归还给其他排队的请求
+	if w.granted {
+		q.inflight--
+		q.dispatch()
+		return 0, err
+	}
+
+	q.waiters[priority].Remove(e)
+	q.waiting--
+	metrics.AddQueueWaiting(q.name, -1)
+	return 0, err
+}
+
+// 排队中的请求数
+func (q *Queue) Waiting() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.waiting
+}
+
+// 直接占用执行名额，不排队，用于重试时切换到其他服务，可能短暂超出并发上限
+func (q *Queue) ForceAcquire() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.inflight++
+}
+
+// 归还执行名额
+func (q *Queue) Release() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.inflight--
+	q.dispatch()
+}
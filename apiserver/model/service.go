@@ -2,41 +2,291 @@ package model
 
 import (
 	"apiserver/client/openserver"
+	"apiserver/config"
+	"apiserver/metrics"
+	"common/logger"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
 )
 
+// 模型服务的转发目标
+type Target struct {
+	ServiceID string
+	IP        string
+	Port      int
+	Weight    uint64       // 权重，由服务算力和负载计算
+	Health    Health       // 健康状态
+	Queue     *Queue       // 所属服务的并发队列
+	inflight  atomic.Int64 // 处理中的请求数
+	retired   atomic.Bool  // 已从模型服务中移除
+}
+
+func (t *Target) Key() string {
+	return fmt.Sprintf("%s:%d", t.IP, t.Port)
+}
+
+func (t *Target) URL() string {
+	return fmt.Sprintf("http://%s:%d", t.IP, t.Port)
+}
+
+// 开始处理请求
+func (t *Target) Acquire() {
+	t.inflight.Add(1)
+	metrics.AddUpstreamInflight(t.ServiceID, t.Key(), 1)
+}
+
+// 请求处理结束
+func (t *Target) Release() {
+	inflight := t.inflight.Add(-1)
+	metrics.AddUpstreamInflight(t.ServiceID, t.Key(), -1)
+
+	if inflight == 0 && t.retired.Load() {
+		metrics.DeleteUpstreamInflight(t.ServiceID, t.Key())
+	}
+}
+
+// 目标已移除，不再输出指标，仍有处理中的请求时在最后一个请求结束后移除
+func (t *Target) retire() {
+	t.retired.Store(true)
+	if t.inflight.Load() == 0 {
+		metrics.DeleteUpstreamInflight(t.ServiceID, t.Key())
+	}
+}
+
+func (t *Target) Inflight() int64 {
+	return t.inflight.Load()
+}
+
+// 转发成功
+func (t *Target) ReportSuccess() {
+	t.Health.ReportSuccess()
+}
+
+// 转发失败，连续失败时摘除
+func (t *Target) ReportFailure() {
+	if t.Health.ReportFailure() {
+		logger.Warn("Target ejected", logger.String("service", t.ServiceID), logger.String("target", t.Key()))
+	}
+}
+
 // 某个模型的所有服务
 
 type Service struct {
 	ID          string
 	Power       uint64
 	Load        uint64
-	SelectIndex int
-	Targets     []*openserver.ServiceTarget
+	MaxInflight int64  // 最多同时处理的请求数，小于等于0表示不限制
+	Queue       *Queue // 达到并发上限后排队，不限制的服务从不排队
+	Targets     []*Target
 }
 
 type Services struct {
-	SelectIndex int
-	Services    []*Service
+	Services []*Service
+	Balancer Balancer
+	targets  []*Target
+}
+
+func NewService(info *openserver.ModelServicesResponse) *Service {
+	service := &Service{
+		ID:          info.ID,
+		Power:       info.Power,
+		Load:        info.Load,
+		MaxInflight: info.MaxInflight,
+	}
+
+	// 未配置并发上限时按算力推算
+	if service.MaxInflight <= 0 {
+		service.MaxInflight = int64(info.Power) * config.GetConcurrency().InflightPerPower
+	}
+
+	for _, target := range info.Targets {
+		service.Targets = append(service.Targets, &Target{
+			ServiceID: info.ID,
+			IP:        target.IP,
+			Port:      target.Port,
+		})
+	}
+
+	// 算力减去平均负载作为剩余能力，平均分配给各副本
+	weight := uint64(1)
+	if service.Power > service.Load {
+		weight = service.Power - service.Load
+	}
+	if count := uint64(len(service.Targets)); count > 0 {
+		weight = max(weight/count, 1)
+	}
+	for _, target := range service.Targets {
+		target.Weight = weight
+	}
+
+	return service
+}
+
+// 处理中的请求数
+func (s *Service) Inflight() int64 {
+	var inflight int64
+	for _, target := range s.Targets {
+		inflight += target.Inflight()
+	}
+	return inflight
+}
+
+// 是否达到并发上限，有请求排队时也视为已满
+func (s *Service) Saturated() bool {
+	if s.MaxInflight <= 0 {
+		return false
+	}
+	return s.Inflight() >= s.MaxInflight || (s.Queue != nil && s.Queue.Waiting() > 0)
 }
 
-func (s *Service) SelectTarget() *openserver.ServiceTarget {
-	s.SelectIndex++
-	count := len(s.Targets)
-	if count == 0 {
+// 排队中的请求数
+func (s *Service) Waiting() int {
+	if s.Queue == nil {
+		return 0
+	}
+	return s.Queue.Waiting()
+}
+
+func (s *Services) Add(service *Service) {
+	s.Services = append(s.Services, service)
+	s.targets = append(s.targets, service.Targets...)
+}
+
+// 按ID查找服务
+func (s *Services) FindService(id string) *Service {
+	if s == nil {
 		return nil
 	}
+	for _, service := range s.Services {
+		if service.ID == id {
+			return service
+		}
+	}
+	return nil
+}
+
+// 查找相同的转发目标
+func (s *Services) FindTarget(serviceID, key string) *Target {
+	for _, target := range s.targets {
+		if target.ServiceID == serviceID && target.Key() == key {
+			return target
+		}
+	}
+	return nil
+}
+
+// 沿用旧的转发目标，保留处理中的请求数等运行状态
+func (s *Services) Inherit(old *Services) {
+	if old == nil {
+		return
+	}
 
-	return s.Targets[s.SelectIndex%count]
+	for _, service := range s.Services {
+		for i, target := range service.Targets {
+			found := old.FindTarget(target.ServiceID, target.Key())
+			if found == nil {
+				continue
+			}
+			found.Weight = target.Weight
+			service.Targets[i] = found
+		}
+	}
+
+	s.targets = s.targets[:0]
+	for _, service := range s.Services {
+		s.targets = append(s.targets, service.Targets...)
+	}
 }
 
-func (s *Services) SelectTarget() *openserver.ServiceTarget {
-	s.SelectIndex++
-	count := len(s.Services)
-	if count == 0 {
+// 只在健康的目标中选择，优先选择未达到并发上限的服务
+// 有流量分配规则时先在分配到的服务中选择，这些服务都不可用时再选择其他服务
+func (s *Services) SelectTarget(split *Split, hashKey, splitKey string, exclude ...*Target) *Target {
+	if split == nil {
+		return s.selectFrom(s.Balancer, hashKey, exclude, nil)
+	}
+
+	if route := split.Pick(splitKey); route != nil {
+		if target := s.selectFrom(route.Balancer, hashKey, exclude, route.Contains); target != nil {
+			return target
+		}
+	}
+
+	return s.selectFrom(s.Balancer, hashKey, exclude, split.Primary)
+}
+
+// 选择镜像目标，只选择健康且未达到并发上限的服务
+func (s *Services) SelectShadow(split *Split) *Target {
+	if split == nil || split.Shadow == nil {
+		return nil
+	}
+
+	now := time.Now()
+	candidates := make([]*Target, 0, len(s.targets))
+	for _, service := range s.Services {
+		if !split.Shadow.Contains(service.ID) || service.Saturated() {
+			continue
+		}
+		for _, target := range service.Targets {
+			if target.Health.Healthy(now) {
+				candidates = append(candidates, target)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
 		return nil
 	}
 
-	service := s.Services[s.SelectIndex%count]
+	return split.Shadow.Balancer.Select(candidates, "")
+}
+
+// 在符合条件的服务中选择，条件为空时不限制
+func (s *Services) selectFrom(balancer Balancer, hashKey string, exclude []*Target, match func(serviceID string) bool) *Target {
+	saturated := make(map[string]bool)
+	waiting := make(map[string]int)
+	for _, service := range s.Services {
+		if service.Saturated() {
+			saturated[service.ID] = true
+			waiting[service.ID] = service.Waiting()
+		}
+	}
+
+	now := time.Now()
+	healthy := make([]*Target, 0, len(s.targets))
+	available := make([]*Target, 0, len(s.targets))
+	for _, target := range s.targets {
+		if match != nil && !match(target.ServiceID) {
+			continue
+		}
+		if target.Health.Healthy(now) && !slices.Contains(exclude, target) {
+			healthy = append(healthy, target)
+			if !saturated[target.ServiceID] {
+				available = append(available, target)
+			}
+		}
+	}
+
+	if len(available) > 0 {
+		return balancer.Select(available, hashKey)
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	// 都已达到并发上限时，在排队最少的服务中选择，请求随后在该服务的队列中等待
+	fewest := waiting[healthy[0].ServiceID]
+	for _, target := range healthy {
+		fewest = min(fewest, waiting[target.ServiceID])
+	}
+	candidates := make([]*Target, 0, len(healthy))
+	for _, target := range healthy {
+		if waiting[target.ServiceID] == fewest {
+			candidates = append(candidates, target)
+		}
+	}
 
-	return service.SelectTarget()
+	return balancer.Select(candidates, hashKey)
 }
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"apiserver/client/openserver"
+	"apiserver/config"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+)
+
+// 模型的流量分配规则，按权重将请求分配到不同的服务，并将部分请求镜像到候选服务
+type Split struct {
+	Routes []*SplitRoute
+	Sticky bool // 按API密钥保持分配结果
+	Shadow *SplitShadow
+	total  uint64
+}
+
+type SplitRoute struct {
+	ServiceIDs []string
+	Weight     uint64
+	Balancer   Balancer // 在分配到的服务中均衡
+}
+
+type SplitShadow struct {
+	ServiceIDs []string
+	Percent    float64
+	Balancer   Balancer
+}
+
+func NewSplit(info *openserver.ModelSplitResponse) *Split {
+	strategy := config.GetBalance().GetStrategy(info.ModelName)
+
+	split := &Split{Sticky: info.Sticky}
+	for _, route := range info.Routes {
+		if route.Weight == 0 || len(route.ServiceIDs) == 0 {
+			continue
+		}
+		split.Routes = append(split.Routes, &SplitRoute{
+			ServiceIDs: route.ServiceIDs,
+			Weight:     route.Weight,
+			Balancer:   NewBalancer(strategy),
+		})
+		split.total += route.Weight
+	}
+
+	if shadow := info.Shadow; shadow != nil && shadow.Percent > 0 && len(shadow.ServiceIDs) > 0 {
+		split.Shadow = &SplitShadow{
+			ServiceIDs: shadow.ServiceIDs,
+			Percent:    shadow.Percent,
+			Balancer:   &RoundRobinBalancer{},
+		}
+	}
+
+	return split
+}
+
+// 沿用服务未变化的分配规则的均衡器状态
+func (s *Split) Inherit(old *Split) {
+	if old == nil {
+		return
+	}
+
+	for i, route := range s.Routes {
+		if i < len(old.Routes) && slices.Equal(route.ServiceIDs, old.Routes[i].ServiceIDs) &&
+			route.Balancer.Strategy() == old.Routes[i].Balancer.Strategy() {
+			route.Balancer = old.Routes[i].Balancer
+		}
+	}
+}
+
+// 按权重选择分配规则，保持分配时相同的API密钥总是得到相同的结果
+func (s *Split) Pick(splitKey string) *SplitRoute {
+	if s.total == 0 {
+		return nil
+	}
+
+	var point uint64
+	if s.Sticky && splitKey != "" {
+		h := fnv.New64a()
+		h.Write([]byte(splitKey))
+		point = mix64(h.Sum64()) % s.total
+	} else {
+		point = rand.Uint64N(s.total)
+	}
+
+	for _, route := range s.Routes {
+		if point < route.Weight {
+			return route
+		}
+		point -= route.Weight
+	}
+	return nil
+}
+
+// 是否参与正常转发，只用于镜像的服务不参与
+func (s *Split) Primary(serviceID string) bool {
+	if s.Shadow == nil || !s.Shadow.Contains(serviceID) {
+		return true
+	}
+
+	for _, route := range s.Routes {
+		if route.Contains(serviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否镜像本次请求
+func (s *Split) Mirror() bool {
+	return s.Shadow != nil && rand.Float64()*100 < s.Shadow.Percent
+}
+
+func (r *SplitRoute) Contains(serviceID string) bool {
+	return slices.Contains(r.ServiceIDs, serviceID)
+}
+
+func (s *SplitShadow) Contains(serviceID string) bool {
+	return slices.Contains(s.ServiceIDs, serviceID)
+}
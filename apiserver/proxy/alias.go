@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"apiserver/model"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 解析模型别名，转发时使用实际模型名称
+func (h *Handler) resolveAlias() {
+	alias := model.ResolveAlias(h.ModelName)
+	if alias == nil {
+		return
+	}
+
+	h.ModelAlias = h.ModelName
+	h.RewriteModel = alias.RewriteResponse
+	h.ModelName = alias.ModelName
+	h.RequestBody["model"] = alias.ModelName
+}
+
+// 将响应中的 model 字段改写为调用方使用的别名
+func (h *Handler) rewriteResponseModel(resp *http.Response) error {
+	if !h.RewriteModel || resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
+	// 压缩或非文本的响应（如语音）不改写
+	if resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	replacer := newModelReplacer(h.ModelName, h.ModelAlias)
+	contentType := resp.Header.Get("Content-Type")
+
+	if strings.Contains(contentType, "text/event-stream") {
+		resp.Body = &lineRewriteReader{body: resp.Body, reader: bufio.NewReader(resp.Body), replacer: replacer}
+		return nil
+	}
+
+	if !strings.Contains(contentType, "json") {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	data = replacer.Replace(data)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return nil
+}
+
+// 按JSON编码后的 model 字段替换，兼容冒号后有无空格两种格式
+type modelReplacer struct {
+	olds [][]byte
+	news [][]byte
+}
+
+func newModelReplacer(modelName, alias string) *modelReplacer {
+	oldName, _ := json.Marshal(modelName)
+	newName, _ := json.Marshal(alias)
+
+	r := &modelReplacer{}
+	for _, sep := range []string{":", ": "} {
+		r.olds = append(r.olds, []byte(`"model"`+sep+string(oldName)))
+		r.news = append(r.news, []byte(`"model"`+sep+string(newName)))
+	}
+	return r
+}
+
+func (r *modelReplacer) Replace(data []byte) []byte {
+	for i := range r.olds {
+		data = bytes.ReplaceAll(data, r.olds[i], r.news[i])
+	}
+	return data
+}
+
+// 逐行改写流式响应
+type lineRewriteReader struct {
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	replacer *modelReplacer
+	pending  []byte
+	err      error
+}
+
+func (r *lineRewriteReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		line, err := r.reader.ReadBytes('\n')
+		r.pending = r.replacer.Replace(line)
+		r.err = err
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *lineRewriteReader) Close() error {
+	return r.body.Close()
+}
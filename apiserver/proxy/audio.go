@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"bytes"
+	"common/logger"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 无法解析音频时，按 128kbps 估算时长
+const defaultAudioBytesPerSecond = 128 * 1000 / 8
+
+// TranscriptionResponse 定义了语音识别响应的结构
+type TranscriptionResponse struct {
+	Duration float64             `json:"duration,omitempty"` // verbose_json 格式返回的时长(秒)
+	Usage    *TranscriptionUsage `json:"usage,omitempty"`
+}
+
+type TranscriptionUsage struct {
+	Type         string  `json:"type"` // tokens 或 duration
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	Seconds      float64 `json:"seconds,omitempty"`
+}
+
+// 语音合成，按输入字符数计量
+
+type SpeechHandler struct {
+	Handler
+}
+
+func NewSpeechHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SpeechHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SpeechHandler) OnAfter(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
+	input, _ := h.RequestBody["input"].(string)
+	units := UsageUnits{Characters: int64(utf8.RuneCountInString(input))}
+	h.recordUnits(units)
+
+	logger.Info("Speech Usage", logger.String("Model", h.ModelName), logger.Int64("Characters", units.Characters))
+	return nil
+}
+
+// 语音识别与翻译，按音频时长计量
+
+type TranscriptionsHandler struct {
+	Handler
+}
+
+func NewTranscriptionsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &TranscriptionsHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *TranscriptionsHandler) OnAfter(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
+	// text、srt 等格式不含时长，直接按上传的音频计算
+	var transcription TranscriptionResponse
+	if strings.Contains(resp.Header.Get("Content-Type"), "application/json") {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return NewResponseError(http.StatusInternalServerError, fmt.Sprintf("Failed to read response body: %v", err))
+		}
+		resp.Body.Close()
+
+		json.Unmarshal(data, &transcription)
+		resp.Body = io.NopCloser(bytes.NewBuffer(data))
+	}
+
+	seconds := transcription.Duration
+	reported := false
+	if usage := transcription.Usage; usage != nil {
+		switch usage.Type {
+		case "duration":
+			seconds = usage.Seconds
+		case "tokens":
+			h.recordUsage(usage.InputTokens, usage.OutputTokens)
+			reported = true
+		}
+	}
+
+	// 上游既未返回时长也未返回使用量时才按上传的音频估算
+	units := UsageUnits{AudioMillis: int64(seconds * 1000)}
+	if units.AudioMillis <= 0 && !reported {
+		units.AudioMillis = h.uploadedAudioMillis()
+		h.markEstimated(true)
+	}
+	h.recordUnits(units)
+
+	logger.Info("Transcription Usage", logger.String("Model", h.ModelName), logger.Int64("AudioMillis", units.AudioMillis))
+	return nil
+}
+
+// 根据上传的音频文件估算时长
+func (h *TranscriptionsHandler) uploadedAudioMillis() int64 {
+	files, ok := h.RequestBody["_files"].(map[string][]*multipart.FileHeader)
+	if !ok {
+		return 0
+	}
+
+	var millis int64
+	for _, fileHeader := range files["file"] {
+		millis += audioMillis(fileHeader)
+	}
+	return millis
+}
+
+// WAV 文件按头部的字节率计算，其他格式按默认码率估算
+func audioMillis(fileHeader *multipart.FileHeader) int64 {
+	bytesPerSecond := int64(defaultAudioBytesPerSecond)
+	dataSize := fileHeader.Size
+
+	if file, err := fileHeader.Open(); err == nil {
+		header := make([]byte, 44)
+		if _, err := io.ReadFull(file, header); err == nil &&
+			string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE" {
+			if byteRate := binary.LittleEndian.Uint32(header[28:32]); byteRate > 0 {
+				bytesPerSecond = int64(byteRate)
+				dataSize -= int64(len(header))
+			}
+		}
+		file.Close()
+	}
+
+	if dataSize <= 0 {
+		return 0
+	}
+	return dataSize * 1000 / bytesPerSecond
+}
@@ -1,7 +1,6 @@
 package proxy
 
 import (
-	"bufio"
 	"bytes"
 	"common/logger"
 	"encoding/json"
@@ -15,23 +14,69 @@ import (
 
 // ChatCompletionUsage 定义了使用量的结构
 type ChatCompletionUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// 输入token明细，开启前缀缓存时返回命中缓存的数量
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
 }
 
 // ChatCompletionResponse 定义了响应的结构
 type ChatCompletionResponse struct {
-	Usage *ChatCompletionUsage `json:"usage,omitempty"`
+	Choices []ChatCompletionChoice `json:"choices,omitempty"`
+	Usage   *ChatCompletionUsage   `json:"usage,omitempty"`
+}
+
+type ChatCompletionChoice struct {
+	Message struct {
+		Content          string `json:"content,omitempty"`
+		ReasoningContent string `json:"reasoning_content,omitempty"`
+	} `json:"message"`
+}
+
+// 输出的文本内容
+func (r *ChatCompletionResponse) Text() string {
+	var buf strings.Builder
+	for _, choice := range r.Choices {
+		buf.WriteString(choice.Message.ReasoningContent)
+		buf.WriteString(choice.Message.Content)
+	}
+	return buf.String()
 }
 
 // ChatCompletionChunk 定义了流式响应chunk的结构
 type ChatCompletionChunk struct {
-	Usage *ChatCompletionUsage `json:"usage,omitempty"`
+	Choices []ChatCompletionChunkChoice `json:"choices,omitempty"`
+	Usage   *ChatCompletionUsage        `json:"usage,omitempty"`
+}
+
+type ChatCompletionChunkChoice struct {
+	Delta struct {
+		Content          string `json:"content,omitempty"`
+		ReasoningContent string `json:"reasoning_content,omitempty"`
+		ToolCalls        []any  `json:"tool_calls,omitempty"`
+	} `json:"delta"`
+}
+
+// 是否包含输出内容
+func (c *ChatCompletionChunk) HasContent() bool {
+	for _, choice := range c.Choices {
+		delta := &choice.Delta
+		if delta.Content != "" || delta.ReasoningContent != "" || len(delta.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 type ChatCompletionsHandler struct {
 	Handler
+	hasUsage bool            // 上游返回了使用量
+	output   strings.Builder // 流式输出内容，上游未返回使用量时用于统计
 }
 
 func NewChatCompletionsHandler() gin.HandlerFunc {
@@ -65,49 +110,20 @@ func (h *ChatCompletionsHandler) OnBefore() error {
 // 统计使用量
 func (h *ChatCompletionsHandler) OnAfter(resp *http.Response) error {
 
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
 	isStream := strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream")
 
 	if isStream {
-		// 流式响应处理
-		originalBody := resp.Body
-		reader, writer := io.Pipe()
-		resp.Body = reader
-
-		go func() {
-			defer writer.Close()
-			defer originalBody.Close()
-
-			var buf bytes.Buffer
-			scanner := bufio.NewScanner(originalBody)
-			var lastChunk ChatCompletionChunk
-
-			for scanner.Scan() {
-				line := scanner.Text()
-				buf.WriteString(line)
-				buf.WriteString("\n")
-
-				if strings.HasPrefix(line, "data: ") {
-					if strings.Contains(line, `"usage"`) {
-						chunk := line[6:] // 去掉 "data: " 前缀
-						if err := json.Unmarshal([]byte(chunk), &lastChunk); err == nil {
-							h.HandleUsage(lastChunk.Usage)
-						}
-					}
-				}
-
-				// 立即写入每一行数据
-				if _, err := writer.Write(buf.Bytes()); err != nil {
-					logger.Error("Failed to write stream data", logger.Err(err))
-					return
-				}
-				buf.Reset()
+		// 流式响应处理，逐个事件转发并统计
+		resp.Body = newEventStreamReader(resp.Body, h.onStreamData, func() {
+			if !h.hasUsage {
+				h.HandleUsage(h.countUsage(h.output.String()))
 			}
-
-			if err := scanner.Err(); err != nil {
-				logger.Error("Scanner error", logger.Err(err))
-			}
-		}()
-
+			h.onStreamEnd(h.GetRequestContext().Err() != nil)
+		})
 	} else {
 		// 非流式响应处理
 		data, err := io.ReadAll(resp.Body)
@@ -119,6 +135,9 @@ func (h *ChatCompletionsHandler) OnAfter(resp *http.Response) error {
 		// 解析使用量信息但不修改原始数据
 		var completionResponse ChatCompletionResponse
 		if err := json.Unmarshal(data, &completionResponse); err == nil {
+			if completionResponse.Usage == nil {
+				completionResponse.Usage = h.countUsage(completionResponse.Text())
+			}
 			h.HandleUsage(completionResponse.Usage)
 		}
 
@@ -129,10 +148,44 @@ func (h *ChatCompletionsHandler) OnAfter(resp *http.Response) error {
 	return nil
 }
 
+// 解析流式数据块
+func (h *ChatCompletionsHandler) onStreamData(data []byte) {
+	var chunk ChatCompletionChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+
+	h.onStreamChunk(chunk.HasContent())
+	h.HandleUsage(chunk.Usage)
+
+	if !h.hasUsage {
+		for _, choice := range chunk.Choices {
+			h.output.WriteString(choice.Delta.ReasoningContent)
+			h.output.WriteString(choice.Delta.Content)
+		}
+	}
+}
+
+// 上游未返回使用量，由网关统计
+func (h *ChatCompletionsHandler) countUsage(output string) *ChatCompletionUsage {
+	usage := &ChatCompletionUsage{
+		PromptTokens:     h.countMessages(h.RequestBody["messages"]),
+		CompletionTokens: h.countText(output),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
 func (h *ChatCompletionsHandler) HandleUsage(usage *ChatCompletionUsage) {
 	if usage == nil {
 		return
 	}
+	h.hasUsage = true
+
+	h.recordUsage(usage.PromptTokens, usage.CompletionTokens)
+	if details := usage.PromptTokensDetails; details != nil {
+		h.recordCachedTokens(details.CachedTokens)
+	}
 
 	logger.Info("Usage",
 		logger.String("Model", h.ModelName),
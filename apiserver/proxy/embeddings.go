@@ -24,25 +24,38 @@ func NewEmbeddingsHandler() gin.HandlerFunc {
 }
 
 func (h *EmbeddingsHandler) OnAfter(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return NewResponseError(http.StatusInternalServerError, fmt.Sprintf("Failed to read response body: %v", err))
 	}
 	resp.Body.Close()
 
+	// 上游未返回使用量，由网关统计
 	var completionResponse ChatCompletionResponse
-	if err := json.Unmarshal(data, &completionResponse); err == nil {
-		h.HandleUsage(completionResponse.Usage)
+	if err := json.Unmarshal(data, &completionResponse); err != nil || completionResponse.Usage == nil {
+		completionResponse.Usage = h.countUsage()
 	}
+	h.HandleUsage(completionResponse.Usage)
 
 	resp.Body = io.NopCloser(bytes.NewBuffer(data))
 	return nil
 }
 
+func (h *EmbeddingsHandler) countUsage() *ChatCompletionUsage {
+	tokens := h.countInput(h.RequestBody["input"])
+	return &ChatCompletionUsage{PromptTokens: tokens, TotalTokens: tokens}
+}
+
 func (h *EmbeddingsHandler) HandleUsage(usage *ChatCompletionUsage) {
 	if usage == nil {
 		return
 	}
 
+	h.recordUsage(usage.TotalTokens, 0)
+
 	logger.Info("Embed Usage", logger.String("Model", h.ModelName), logger.Int("PromptTokens", usage.PromptTokens), logger.Int("TotalTokens", usage.TotalTokens))
 }
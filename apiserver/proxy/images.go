@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+	"common/logger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 默认图片分辨率
+const (
+	defaultImageWidth  = 1024
+	defaultImageHeight = 1024
+)
+
+// ImagesResponse 定义了图片生成响应的结构
+type ImagesResponse struct {
+	Data []json.RawMessage `json:"data"`
+}
+
+type ImagesHandler struct {
+	Handler
+}
+
+func NewImagesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ImagesHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+// 按生成图片的数量和分辨率计量
+func (h *ImagesHandler) OnAfter(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return NewResponseError(http.StatusInternalServerError, fmt.Sprintf("Failed to read response body: %v", err))
+	}
+	resp.Body.Close()
+
+	// 无法解析响应时按请求数量计算
+	count := int64(h.requestNumber())
+	var imagesResponse ImagesResponse
+	if err := json.Unmarshal(data, &imagesResponse); err == nil {
+		count = int64(len(imagesResponse.Data))
+	}
+
+	width, height := h.requestSize()
+	units := UsageUnits{ImageCount: count, ImagePixels: count * int64(width) * int64(height)}
+	h.recordUnits(units)
+
+	prompt, _ := h.RequestBody["prompt"].(string)
+	h.recordUsage(h.countText(prompt), 0)
+
+	logger.Info("Image Usage",
+		logger.String("Model", h.ModelName),
+		logger.Int64("ImageCount", units.ImageCount),
+		logger.Int64("ImagePixels", units.ImagePixels))
+
+	resp.Body = io.NopCloser(bytes.NewBuffer(data))
+	return nil
+}
+
+// 请求的图片数量，默认一张
+func (h *ImagesHandler) requestNumber() int {
+	switch n := h.RequestBody["n"].(type) {
+	case float64:
+		return max(int(n), 1)
+	case string:
+		if value, err := strconv.Atoi(n); err == nil {
+			return max(value, 1)
+		}
+	}
+	return 1
+}
+
+// 请求的分辨率，格式为 宽x高
+func (h *ImagesHandler) requestSize() (int, int) {
+	size, _ := h.RequestBody["size"].(string)
+	widthText, heightText, ok := strings.Cut(size, "x")
+	if !ok {
+		return defaultImageWidth, defaultImageHeight
+	}
+
+	width, err1 := strconv.Atoi(widthText)
+	height, err2 := strconv.Atoi(heightText)
+	if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return defaultImageWidth, defaultImageHeight
+	}
+
+	return width, height
+}
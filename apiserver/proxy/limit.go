@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"apiserver/limiter"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 检查调用限制，依次检查用户所有工作空间的汇总限制、密钥限制和工作空间模型限制
+func (h *Handler) checkUsageLimit() *ResponseError {
+	workspace := h.ApiKeyInfo.WorkspaceInfo
+	usageLimit := workspace.FindUsageLimit(h.ModelName)
+	if usageLimit == nil {
+		return NewResponseError(http.StatusForbidden, fmt.Sprintf("The model `%s` is not granted to this workspace", h.ModelName))
+	}
+
+	if !h.ApiKeyInfo.AllowModel(h.ModelName) {
+		return NewResponseError(http.StatusForbidden, fmt.Sprintf("The model `%s` is not allowed for this API key", h.ModelName))
+	}
+
+	var rules []limiter.Rule
+	if userLimit := h.ApiKeyInfo.UserLimit; userLimit != nil {
+		rules = append(rules, limiter.Rule{
+			Key:          limiter.UserKey(h.ApiKeyInfo.UserID),
+			Scope:        limiter.ScopeUser,
+			RequestLimit: userLimit.RequestLimit,
+			TokenLimit:   userLimit.TokenLimit,
+		})
+	}
+	if keyInfo := h.ApiKeyInfo; keyInfo.RequestLimit > 0 || keyInfo.TokenLimit > 0 {
+		rules = append(rules, limiter.Rule{
+			Key:          limiter.KeyKey(keyInfo.ID),
+			Scope:        limiter.ScopeKey,
+			RequestLimit: keyInfo.RequestLimit,
+			TokenLimit:   keyInfo.TokenLimit,
+		})
+	}
+	rules = append(rules, limiter.Rule{
+		Key:          limiter.WorkspaceKey(workspace.ID, h.ModelName),
+		Scope:        limiter.ScopeWorkspace,
+		RequestLimit: usageLimit.RequestLimit,
+		TokenLimit:   usageLimit.TokenLimit,
+	})
+
+	result := limiter.AllowAll(h.GetRequestContext(), rules...)
+	h.setRateLimitHeaders(result)
+
+	if !result.Allowed {
+		retryAfter := int64(math.Ceil(result.RetryAfter().Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		h.GinContext.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+
+		if result.RequestLimit > 0 && result.RequestRemaining <= 0 {
+			return NewResponseError(http.StatusTooManyRequests, fmt.Sprintf("Rate limit reached for %s requests: limit %d per minute", result.Scope, result.RequestLimit))
+		}
+
+		return NewResponseError(http.StatusTooManyRequests, fmt.Sprintf("Rate limit reached for %s tokens: limit %d per minute", result.Scope, result.TokenLimit))
+	}
+
+	h.LimitRules = rules
+	return nil
+}
+
+// 设置限流响应头
+func (h *Handler) setRateLimitHeaders(result *limiter.Result) {
+	c := h.GinContext
+
+	if result.RequestLimit > 0 {
+		c.Header("x-ratelimit-limit-requests", strconv.FormatInt(result.RequestLimit, 10))
+		c.Header("x-ratelimit-remaining-requests", strconv.FormatInt(max(result.RequestRemaining, 0), 10))
+		c.Header("x-ratelimit-reset-requests", formatReset(result.RequestReset))
+	}
+
+	if result.TokenLimit > 0 {
+		c.Header("x-ratelimit-limit-tokens", strconv.FormatInt(result.TokenLimit, 10))
+		c.Header("x-ratelimit-remaining-tokens", strconv.FormatInt(max(result.TokenRemaining, 0), 10))
+		c.Header("x-ratelimit-reset-tokens", formatReset(result.TokenReset))
+	}
+}
+
+// 扣减Token用量
+func (h *Handler) debitTokens(tokens int) {
+	if len(h.LimitRules) == 0 {
+		return
+	}
+	limiter.Consume(context.WithoutCancel(h.GetRequestContext()), int64(tokens), h.LimitRules...)
+}
+
+func formatReset(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"apiserver/model"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 兼容OpenAI的模型列表接口
+
+type ModelObject struct {
+	ID          string   `json:"id"`
+	Object      string   `json:"object"`
+	Created     int64    `json:"created"`
+	OwnedBy     string   `json:"owned_by"`
+	MaxModelLen uint64   `json:"max_model_len,omitempty"`
+	Classes     []uint64 `json:"classes,omitempty"`
+	Abilities   []uint64 `json:"abilities,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+type ModelList struct {
+	Object string        `json:"object"`
+	Data   []ModelObject `json:"data"`
+}
+
+type ModelsHandler struct {
+	Handler
+}
+
+func NewModelListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ModelsHandler{}
+		h.OnList(c)
+	}
+}
+
+func NewModelRetrieveHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ModelsHandler{}
+		h.OnRetrieve(c)
+	}
+}
+
+// 列出工作空间已授权的模型
+func (h *ModelsHandler) OnList(c *gin.Context) {
+	h.GinContext = c
+
+	if err := h.checkApiKey(); err != nil {
+		c.AbortWithStatusJSON(err.Data.Code, err)
+		return
+	}
+
+	response := ModelList{Object: "list", Data: []ModelObject{}}
+	for _, name := range model.ModelNames() {
+		if h.isGranted(name) {
+			response.Data = append(response.Data, newModelObject(name, name))
+		}
+	}
+
+	// 别名按实际模型的授权展示
+	for _, alias := range model.Aliases() {
+		if h.isGranted(alias.ModelName) {
+			response.Data = append(response.Data, newModelObject(alias.Alias, alias.ModelName))
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// 查询指定模型
+func (h *ModelsHandler) OnRetrieve(c *gin.Context) {
+	h.GinContext = c
+
+	if err := h.checkApiKey(); err != nil {
+		c.AbortWithStatusJSON(err.Data.Code, err)
+		return
+	}
+
+	// 模型名称可能包含斜杠，如 Qwen/Qwen3-1.7B
+	name := strings.TrimPrefix(c.Param("id"), "/")
+	modelName := name
+	if alias := model.ResolveAlias(name); alias != nil {
+		modelName = alias.ModelName
+	}
+
+	for _, found := range model.ModelNames() {
+		if found == modelName && h.isGranted(modelName) {
+			c.JSON(http.StatusOK, newModelObject(name, modelName))
+			return
+		}
+	}
+
+	c.AbortWithStatusJSON(http.StatusNotFound, NewResponseError(http.StatusNotFound, fmt.Sprintf("The model `%s` does not exist", name)))
+}
+
+func (h *ModelsHandler) isGranted(modelName string) bool {
+	workspace := h.ApiKeyInfo.WorkspaceInfo
+	return workspace != nil && workspace.FindUsageLimit(modelName) != nil && h.ApiKeyInfo.AllowModel(modelName)
+}
+
+// 别名使用实际模型的元数据
+func newModelObject(id, modelName string) ModelObject {
+	object := ModelObject{
+		ID:      id,
+		Object:  "model",
+		OwnedBy: "system",
+	}
+
+	// 补充预置模型元数据
+	if info := model.FindInfo(modelName); info != nil {
+		object.Created = info.CreatedAt.Unix()
+		object.MaxModelLen = info.MaxContextLength
+		object.Classes = info.Classes
+		object.Abilities = info.Abilities
+		object.Description = info.Description
+		if info.ProviderName != "" {
+			object.OwnedBy = info.ProviderName
+		}
+	}
+
+	return object
+}
@@ -1,19 +1,25 @@
 package proxy
 
 import (
+	"apiserver/limiter"
+	"apiserver/metrics"
 	"apiserver/model"
 	"apiserver/user"
 	"bytes"
 	"common/logger"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,13 +37,29 @@ type TaskInterface interface {
 }
 
 type Handler struct {
-	GinContext  *gin.Context
-	Task        TaskInterface
-	RequestBody map[string]any
-	ModelName   string
-	ApiKey      string
-	ApiKeyInfo  *user.ApiKeyInfo
-	TargetURL   *url.URL
+	GinContext   *gin.Context
+	Task         TaskInterface
+	RequestBody  map[string]any
+	ModelName    string
+	ModelAlias   string // 调用方使用的模型别名，未使用别名时为空
+	RewriteModel bool   // 响应中的 model 字段改写为别名
+	ApiKey       string
+	ApiKeyInfo   *user.ApiKeyInfo
+	ClientIP     string         // 来源地址，只采信受信任代理转发的请求头
+	LimitRules   []limiter.Rule // 通过的限流规则，用于扣减Token
+	ServiceID    string
+	Queue        *model.Queue // 占用的并发名额
+	Target       *model.Target
+	TargetURL    *url.URL
+	Attempts     int // 转发尝试次数
+	StartTime    time.Time
+	InputTokens  int64
+	OutputTokens int64
+	CachedTokens int64      // 命中缓存的输入token
+	Units        UsageUnits // 非token计费单位
+	Estimated    bool       // 使用量由网关统计，而非上游返回
+	Stream       StreamStats
+	usageMutex   sync.Mutex
 }
 
 func NewDefaultHandler() gin.HandlerFunc {
@@ -63,25 +85,55 @@ func (h *Handler) OnAfter(resp *http.Response) error {
 func (h *Handler) OnRequest(c *gin.Context) {
 
 	h.GinContext = c
+	h.StartTime = time.Now()
 
 	// 检查API密钥
 	if err := h.checkApiKey(); err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, err)
+		c.AbortWithStatusJSON(err.Data.Code, err)
 		return
 	}
 
+	// 记录调用日志
+	defer h.addUsageLog()
+
 	// 检查模型名称
 	if err := h.checkModelName(); err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, err)
 		return
 	}
 
+	// 检查账户余额
+	if h.ApiKeyInfo.BalanceExhausted() {
+		c.AbortWithStatusJSON(http.StatusPaymentRequired, NewResponseError(http.StatusPaymentRequired, "Insufficient balance, please top up your account"))
+		return
+	}
+
+	// 检查调用限制
+	if err := h.checkUsageLimit(); err != nil {
+		c.AbortWithStatusJSON(err.Data.Code, err)
+		return
+	}
+
 	// 选择转发目标
 	if err := h.selectTarget(); err != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, err)
+		c.AbortWithStatusJSON(err.Data.Code, err)
+		return
+	}
+
+	// 目标服务达到并发上限时排队
+	if err := h.acquireQueue(); err != nil {
+		c.AbortWithStatusJSON(err.Data.Code, err)
 		return
 	}
 
+	defer h.releaseQueue()
+
+	// 重试时会切换目标，释放最终使用的目标
+	h.Target.Acquire()
+	defer func() {
+		h.Target.Release()
+	}()
+
 	// 转发前处理
 	if err := h.Task.OnBefore(); err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, err)
@@ -91,8 +143,16 @@ func (h *Handler) OnRequest(c *gin.Context) {
 	// 重新设置请求体
 	h.setbackBody()
 
+	// 镜像部分请求到候选服务
+	h.mirrorRequest()
+
+	// 上游已返回响应，之后网关处理响应出错不计入上游健康状态
+	responded := false
+
 	proxy := &httputil.ReverseProxy{
-		Transport: sharedTransport,
+		Transport: &retryTransport{handler: h},
+		// 流式响应逐个事件立即刷新
+		FlushInterval: -1,
 		Director: func(req *http.Request) {
 			req.Host = h.TargetURL.Host
 			req.URL.Scheme = h.TargetURL.Scheme
@@ -101,9 +161,23 @@ func (h *Handler) OnRequest(c *gin.Context) {
 			req.URL.RawQuery = c.Request.URL.RawQuery
 		},
 		ModifyResponse: func(resp *http.Response) error {
-			return h.Task.OnAfter(resp)
+			err := h.Task.OnAfter(resp)
+			if err == nil {
+				err = h.rewriteResponseModel(resp)
+			}
+
+			responded = true
+			if resp.StatusCode >= http.StatusInternalServerError {
+				h.Target.ReportFailure()
+			} else {
+				h.Target.ReportSuccess()
+			}
+			return err
 		},
 		ErrorHandler: func(rw http.ResponseWriter, req *http.Request, err error) {
+			if !responded && req.Context().Err() == nil {
+				h.Target.ReportFailure()
+			}
 			logger.Error("ReverseProxy", logger.String("HOST", req.Host), logger.String("URI", req.RequestURI), logger.Err(err))
 			rw.WriteHeader(http.StatusBadGateway)
 			json.NewEncoder(rw).Encode(NewResponseError(http.StatusBadGateway, err.Error()))
@@ -134,10 +208,34 @@ func (h *Handler) checkApiKey() *ResponseError {
 		return NewResponseError(http.StatusUnauthorized, "API KEY required")
 	}
 
+	h.ClientIP = c.ClientIP()
+
+	// 认证失败过多的来源地址暂时拒绝，来源地址不受客户端伪造的转发请求头影响
+	if wait, blocked := user.AuthBlocked(h.ClientIP); blocked {
+		metrics.AuthThrottled()
+		c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(wait.Seconds())), 10))
+		return NewResponseError(http.StatusTooManyRequests, "Too many failed authentication attempts")
+	}
+
 	var err error
 	h.ApiKeyInfo, err = user.FindKey(h.GetRequestContext(), h.ApiKey)
+	if errors.Is(err, user.ErrKeyNotFound) {
+		user.AuthFailed(h.ClientIP)
+		return NewResponseError(http.StatusUnauthorized, "Invalid API KEY")
+	}
+
 	if err != nil {
-		return NewResponseError(http.StatusUnauthorized, err.Error())
+		logger.Error("FindKey", logger.Err(err))
+		return NewResponseError(http.StatusServiceUnavailable, "API KEY verification unavailable")
+	}
+
+	// 检查密钥的来源地址和接口范围
+	if !h.ApiKeyInfo.AllowIP(h.ClientIP) {
+		return NewResponseError(http.StatusForbidden, "Client IP is not allowed for this API key")
+	}
+
+	if scope := routeScope(c.FullPath()); scope != "" && !h.ApiKeyInfo.AllowScope(scope) {
+		return NewResponseError(http.StatusForbidden, fmt.Sprintf("The API key does not have the `%s` scope", scope))
 	}
 
 	return nil
@@ -173,6 +271,7 @@ func (h *Handler) checkModelName() *ResponseError {
 			}
 			h.RequestBody["_files"] = form.File
 		}
+		h.resolveAlias()
 		return nil
 	}
 
@@ -194,6 +293,7 @@ func (h *Handler) checkModelName() *ResponseError {
 		return NewResponseError(http.StatusBadRequest, "Model name is required")
 	}
 
+	h.resolveAlias()
 	return nil
 }
 
@@ -235,26 +335,65 @@ func (h *Handler) setbackBody() {
 
 		writer.Close()
 		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
-		c.Request.ContentLength = int64(body.Len())
-		c.Request.Body = io.NopCloser(body)
+		h.setRequestData(body.Bytes())
 		return
 	}
 
 	// 对于 JSON 格式，重新设置请求体
 	data, _ := json.Marshal(h.RequestBody)
 	c.Request.Header.Set("Content-Type", "application/json")
+	h.setRequestData(data)
+}
+
+// 设置请求体，GetBody 用于重试时重新读取
+func (h *Handler) setRequestData(data []byte) {
+	c := h.GinContext
 	c.Request.ContentLength = int64(len(data))
-	c.Request.Body = io.NopCloser(bytes.NewBuffer(data))
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	c.Request.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
 }
 
 // 选择转发目标
 func (h *Handler) selectTarget() *ResponseError {
-	target := model.SelectTarget(h.ModelName)
+	target := model.SelectTarget(h.ModelName, h.sessionKey(), h.ApiKeyInfo.ID)
 	if target == nil {
-		return NewResponseError(http.StatusBadGateway, "Not found target")
+		return NewResponseError(http.StatusServiceUnavailable, "No available target")
 	}
 
-	h.TargetURL, _ = url.Parse(fmt.Sprintf("http://%s:%d", target.IP, target.Port))
+	h.Target = target
+	h.ServiceID = target.ServiceID
+	h.TargetURL, _ = url.Parse(target.URL())
+
+	// 模型已部署，作为指标标签
+	h.GinContext.Set(metrics.ModelKey, h.ModelName)
 
 	return nil
 }
+
+// 切换转发目标，切换到其他服务时转移并发名额
+func (h *Handler) switchTarget(target *model.Target) {
+	h.Target.Release()
+
+	if h.Queue != target.Queue {
+		h.releaseQueue()
+		h.Queue = target.Queue
+		if h.Queue != nil {
+			h.Queue.ForceAcquire()
+		}
+	}
+
+	h.Target = target
+	h.ServiceID = target.ServiceID
+	h.TargetURL, _ = url.Parse(target.URL())
+	h.Target.Acquire()
+}
+
+// 会话标识，用于一致性哈希
+func (h *Handler) sessionKey() string {
+	if user, ok := h.RequestBody["user"].(string); ok && user != "" {
+		return user
+	}
+	return h.GinContext.GetHeader("X-Session-ID")
+}
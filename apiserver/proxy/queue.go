@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"apiserver/config"
+	"apiserver/metrics"
+	"apiserver/model"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 转发目标所属服务达到并发上限时排队，按工作空间服务等级确定优先级
+func (h *Handler) acquireQueue() *ResponseError {
+	queue := h.Target.Queue
+	if queue == nil {
+		return nil
+	}
+
+	cfg := config.GetConcurrency()
+	wait, err := queue.Acquire(h.GetRequestContext(), h.ApiKeyInfo.WorkspaceInfo.Tier, cfg.QueueSize, cfg.QueueTimeout)
+	if err != nil {
+		reason := "canceled"
+		switch {
+		case errors.Is(err, model.ErrQueueFull):
+			reason = "full"
+		case errors.Is(err, model.ErrQueueTimeout):
+			reason = "timeout"
+		}
+		metrics.QueueRejected(h.ModelName, reason)
+
+		h.GinContext.Header("Retry-After", "1")
+		return NewResponseError(http.StatusServiceUnavailable, fmt.Sprintf("The model `%s` is overloaded, please retry later", h.ModelName))
+	}
+
+	if wait > 0 {
+		metrics.ObserveQueueWait(h.ModelName, wait)
+	}
+
+	h.Queue = queue
+	return nil
+}
+
+// 归还并发名额
+func (h *Handler) releaseQueue() {
+	if h.Queue != nil {
+		h.Queue.Release()
+	}
+}
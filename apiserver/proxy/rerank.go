@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,25 +25,54 @@ func NewRerankHandler() gin.HandlerFunc {
 }
 
 func (h *RerankHandler) OnAfter(resp *http.Response) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil
+	}
+
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return NewResponseError(http.StatusInternalServerError, fmt.Sprintf("Failed to read response body: %v", err))
 	}
 	resp.Body.Close()
 
+	// 上游未返回使用量，由网关统计
 	var completionResponse ChatCompletionResponse
-	if err := json.Unmarshal(data, &completionResponse); err == nil {
-		h.HandleUsage(completionResponse.Usage)
+	if err := json.Unmarshal(data, &completionResponse); err != nil || completionResponse.Usage == nil {
+		completionResponse.Usage = h.countUsage()
 	}
+	h.HandleUsage(completionResponse.Usage)
 
 	resp.Body = io.NopCloser(bytes.NewBuffer(data))
 	return nil
 }
 
+// 每个文档都与查询拼接后计算
+func (h *RerankHandler) countUsage() *ChatCompletionUsage {
+	var texts []string
+	if documents, ok := h.RequestBody["documents"].([]any); ok {
+		for _, document := range documents {
+			switch document := document.(type) {
+			case string:
+				texts = append(texts, document)
+			case map[string]any:
+				if text, ok := document["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+	}
+
+	query, _ := h.RequestBody["query"].(string)
+	tokens := h.countText(query)*len(texts) + h.countText(strings.Join(texts, "\n"))
+	return &ChatCompletionUsage{PromptTokens: tokens, TotalTokens: tokens}
+}
+
 func (h *RerankHandler) HandleUsage(usage *ChatCompletionUsage) {
 	if usage == nil {
 		return
 	}
 
+	h.recordUsage(usage.TotalTokens, 0)
+
 	logger.Info("Rerank Usage", logger.String("Model", h.ModelName), logger.Int("TotalTokens", usage.TotalTokens))
 }
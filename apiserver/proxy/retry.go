@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"apiserver/config"
+	"apiserver/metrics"
+	"apiserver/model"
+	"common/logger"
+	"net/http"
+	"time"
+)
+
+// 转发失败时换目标重试
+// 仅在上游返回响应之前重试：连接失败，或返回 502/503，此时尚未向客户端写出任何内容
+type retryTransport struct {
+	handler *Handler
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.handler
+	maxAttempts := config.GetRetry().MaxAttempts
+	tried := []*model.Target{h.Target}
+
+	for {
+		h.Attempts++
+		start := time.Now()
+		resp, err := sharedTransport.RoundTrip(req)
+		observeUpstream(h, resp, time.Since(start))
+		if h.Attempts >= maxAttempts || req.GetBody == nil || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		next := model.SelectTarget(h.ModelName, h.sessionKey(), h.ApiKeyInfo.ID, tried...)
+		if next == nil {
+			return resp, err
+		}
+
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return resp, err
+		}
+
+		// 放弃当前目标的响应
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		logger.Warn("Retry on another target",
+			logger.String("model", h.ModelName),
+			logger.String("from", h.Target.Key()),
+			logger.String("to", next.Key()),
+			logger.Int("attempt", h.Attempts))
+
+		h.Target.ReportFailure()
+		h.switchTarget(next)
+		tried = append(tried, next)
+
+		req = req.Clone(req.Context())
+		req.Body = body
+		req.Host = h.TargetURL.Host
+		req.URL.Scheme = h.TargetURL.Scheme
+		req.URL.Host = h.TargetURL.Host
+	}
+}
+
+func observeUpstream(h *Handler, resp *http.Response, duration time.Duration) {
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode
+	}
+	metrics.ObserveUpstream(h.ModelName, h.Target.Key(), statusCode, duration)
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	// 客户端已断开
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
@@ -0,0 +1,37 @@
+package proxy
+
+import "strings"
+
+// 密钥可调用的接口范围，与开放平台一致
+const (
+	ScopeChat       = "chat"
+	ScopeEmbeddings = "embeddings"
+	ScopeRerank     = "rerank"
+	ScopeClassify   = "classify"
+	ScopeTokenize   = "tokenize"
+	ScopeAudio      = "audio"
+	ScopeImages     = "images"
+	ScopeModels     = "models"
+	ScopeUsage      = "usage"
+)
+
+// 路由对应的接口范围
+var routeScopes = map[string]string{
+	"/v1/chat/completions":   ScopeChat,
+	"/v1/embeddings":         ScopeEmbeddings,
+	"/v1/rerank":             ScopeRerank,
+	"/classify":              ScopeClassify,
+	"/tokenize":              ScopeTokenize,
+	"/v1/images/generations": ScopeImages,
+	"/v1/models":             ScopeModels,
+	"/v1/models/*id":         ScopeModels,
+	"/v1/usage/summary":      ScopeUsage,
+}
+
+// 查找路由的接口范围，未登记的路由返回空
+func routeScope(fullPath string) string {
+	if strings.HasPrefix(fullPath, "/v1/audio/") {
+		return ScopeAudio
+	}
+	return routeScopes[fullPath]
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"apiserver/config"
+	"apiserver/metrics"
+	"apiserver/model"
+	"common/logger"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// 进行中的镜像请求数
+var shadowInflight atomic.Int64
+
+// 按流量分配规则将请求镜像到候选服务，响应直接丢弃，不影响原请求、计费和目标健康状态
+func (h *Handler) mirrorRequest() {
+	target := model.SelectShadow(h.ModelName)
+	if target == nil {
+		return
+	}
+
+	cfg := config.GetShadow()
+	if shadowInflight.Add(1) > int64(cfg.MaxInflight) {
+		shadowInflight.Add(-1)
+		metrics.ObserveShadow(h.ModelName, "", 0)
+		return
+	}
+
+	req := h.GinContext.Request
+	body, err := req.GetBody()
+	if err != nil {
+		shadowInflight.Add(-1)
+		return
+	}
+
+	// 原请求结束后镜像请求继续执行，直到超时
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), cfg.Timeout)
+	shadowReq, err := http.NewRequestWithContext(ctx, req.Method, target.URL()+req.URL.RequestURI(), body)
+	if err != nil {
+		cancel()
+		shadowInflight.Add(-1)
+		return
+	}
+	shadowReq.Header = req.Header.Clone()
+	shadowReq.ContentLength = req.ContentLength
+
+	modelName := h.ModelName
+	target.Acquire()
+
+	go func() {
+		defer shadowInflight.Add(-1)
+		defer target.Release()
+		defer cancel()
+
+		statusCode := 0
+		resp, err := sharedTransport.RoundTrip(shadowReq)
+		if err == nil {
+			statusCode = resp.StatusCode
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else {
+			logger.Debug("Shadow request failed", logger.String("model", modelName), logger.String("target", target.Key()), logger.Err(err))
+		}
+
+		metrics.ObserveShadow(modelName, target.Key(), statusCode)
+	}()
+}
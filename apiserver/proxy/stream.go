@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"apiserver/metrics"
+	"bufio"
+	"bytes"
+	"common/logger"
+	"io"
+	"sync"
+	"time"
+)
+
+// 流式响应统计
+type StreamStats struct {
+	Stream       bool
+	FirstToken   time.Duration // 首个内容块耗时
+	Duration     time.Duration // 流式响应总耗时
+	Disconnected bool          // 客户端中途断开
+	lastChunk    time.Time
+}
+
+// 输出速度，按首个内容块之后的耗时计算
+func (s *StreamStats) TokensPerSecond(outputTokens int64) float64 {
+	decode := s.Duration - s.FirstToken
+	if s.FirstToken <= 0 || decode <= 0 || outputTokens <= 0 {
+		return 0
+	}
+	return float64(outputTokens) / decode.Seconds()
+}
+
+// 收到流式数据块
+func (h *Handler) onStreamChunk(hasContent bool) {
+	h.usageMutex.Lock()
+	defer h.usageMutex.Unlock()
+
+	now := time.Now()
+	h.Stream.Stream = true
+	if !hasContent {
+		return
+	}
+
+	if h.Stream.lastChunk.IsZero() {
+		h.Stream.FirstToken = now.Sub(h.StartTime)
+		metrics.ObserveFirstToken(h.ModelName, h.Stream.FirstToken)
+	} else {
+		metrics.ObserveInterToken(h.ModelName, now.Sub(h.Stream.lastChunk))
+	}
+	h.Stream.lastChunk = now
+}
+
+// 流式响应结束
+func (h *Handler) onStreamEnd(disconnected bool) {
+	h.usageMutex.Lock()
+	defer h.usageMutex.Unlock()
+
+	h.Stream.Stream = true
+	h.Stream.Duration = time.Since(h.StartTime)
+	h.Stream.Disconnected = disconnected
+
+	logger.Info("Stream",
+		logger.String("Model", h.ModelName),
+		logger.String("Target", h.Target.Key()),
+		logger.Int64("FirstTokenMs", h.Stream.FirstToken.Milliseconds()),
+		logger.Int64("DurationMs", h.Stream.Duration.Milliseconds()),
+		logger.Any("TokensPerSecond", h.Stream.TokensPerSecond(h.OutputTokens)),
+		logger.Any("Disconnected", disconnected))
+}
+
+// 流式响应读取器
+// 由转发代理同步读取，每次返回一个完整事件，行长度不受限制；
+// 客户端断开时转发请求被取消，读取随即失败，不会继续读取上游
+type eventStreamReader struct {
+	body      io.ReadCloser
+	reader    *bufio.Reader
+	event     bytes.Buffer
+	pending   []byte            // 尚未返回的事件数据
+	err       error             // 读取上游的错误
+	onData    func(data []byte) // 收到 data 字段
+	onClose   func()            // 响应结束
+	closeOnce sync.Once
+}
+
+func newEventStreamReader(body io.ReadCloser, onData func(data []byte), onClose func()) *eventStreamReader {
+	return &eventStreamReader{
+		body:    body,
+		reader:  bufio.NewReader(body),
+		onData:  onData,
+		onClose: onClose,
+	}
+}
+
+func (r *eventStreamReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.readEvent()
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *eventStreamReader) Close() error {
+	r.closeOnce.Do(r.onClose)
+	return r.body.Close()
+}
+
+// 读取一个事件，以空行结束
+func (r *eventStreamReader) readEvent() {
+	r.event.Reset()
+
+	for {
+		line, err := r.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			r.event.Write(line)
+			r.handleLine(line)
+		}
+
+		if err != nil {
+			r.err = err
+			break
+		}
+
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			break
+		}
+	}
+
+	r.pending = r.event.Bytes()
+}
+
+func (r *eventStreamReader) handleLine(line []byte) {
+	line = bytes.TrimRight(line, "\r\n")
+
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("[DONE]")) {
+		return
+	}
+
+	r.onData(data)
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"apiserver/metrics"
+	"apiserver/tokenizer"
+	"apiserver/user"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 非token计费单位
+type UsageUnits struct {
+	ImageCount  int64 // 生成图片数量
+	ImagePixels int64 // 生成图片总像素，数量乘以分辨率
+	AudioMillis int64 // 音频时长(毫秒)
+	Characters  int64 // 语音合成的字符数
+}
+
+// 记录调用使用量
+func (h *Handler) recordUsage(inputTokens, outputTokens int) {
+	h.debitTokens(inputTokens + outputTokens)
+	metrics.AddTokens(h.ModelName, inputTokens, outputTokens)
+
+	h.usageMutex.Lock()
+	defer h.usageMutex.Unlock()
+	h.InputTokens += int64(inputTokens)
+	h.OutputTokens += int64(outputTokens)
+}
+
+// 记录命中缓存的输入token数量，已包含在输入token中
+func (h *Handler) recordCachedTokens(cachedTokens int) {
+	h.usageMutex.Lock()
+	defer h.usageMutex.Unlock()
+	h.CachedTokens += int64(cachedTokens)
+}
+
+// 记录非token计费单位
+func (h *Handler) recordUnits(units UsageUnits) {
+	h.usageMutex.Lock()
+	defer h.usageMutex.Unlock()
+	h.Units.ImageCount += units.ImageCount
+	h.Units.ImagePixels += units.ImagePixels
+	h.Units.AudioMillis += units.AudioMillis
+	h.Units.Characters += units.Characters
+}
+
+// 上游未返回使用量时，统计文本token数量
+func (h *Handler) countText(text string) int {
+	count, estimated := tokenizer.CountText(h.tokenizeContext(), h.Target.URL(), h.ModelName, text)
+	h.markEstimated(estimated)
+	return count
+}
+
+// 上游未返回使用量时，统计对话消息token数量
+func (h *Handler) countMessages(messages any) int {
+	count, estimated := tokenizer.CountMessages(h.tokenizeContext(), h.Target.URL(), h.ModelName, messages)
+	h.markEstimated(estimated)
+	return count
+}
+
+// 统计输入的token数量，输入可以是文本、文本数组或token数组
+func (h *Handler) countInput(input any) int {
+	switch value := input.(type) {
+	case string:
+		return h.countText(value)
+	case []any:
+		var texts []string
+		tokens := 0
+		for _, item := range value {
+			switch item := item.(type) {
+			case string:
+				texts = append(texts, item)
+			case float64:
+				tokens++
+			case []any:
+				tokens += len(item)
+			}
+		}
+		if len(texts) > 0 {
+			tokens += h.countText(strings.Join(texts, "\n"))
+		}
+		return tokens
+	}
+	return 0
+}
+
+// 客户端断开后仍需完成计数
+func (h *Handler) tokenizeContext() context.Context {
+	return context.WithoutCancel(h.GetRequestContext())
+}
+
+func (h *Handler) markEstimated(estimated bool) {
+	if !estimated {
+		return
+	}
+
+	h.usageMutex.Lock()
+	defer h.usageMutex.Unlock()
+	h.Estimated = true
+}
+
+// 生成调用日志
+func (h *Handler) addUsageLog() {
+	usageLog := &user.UsageLog{
+		ModelName:    h.ModelName,
+		ServiceID:    h.ServiceID,
+		Status:       user.UsageSuccess,
+		ResponseTime: time.Since(h.StartTime).Milliseconds(),
+		Attempts:     h.Attempts,
+		ClientIP:     h.ClientIP,
+	}
+
+	if h.Target != nil {
+		usageLog.Target = h.Target.Key()
+	}
+
+	if h.GinContext.Writer.Status() >= http.StatusBadRequest {
+		usageLog.Status = user.UsageFailed
+	}
+
+	h.usageMutex.Lock()
+	usageLog.InputTokens = h.InputTokens
+	usageLog.OutputTokens = h.OutputTokens
+	usageLog.CachedTokens = h.CachedTokens
+	usageLog.ImageCount = h.Units.ImageCount
+	usageLog.ImagePixels = h.Units.ImagePixels
+	usageLog.AudioMillis = h.Units.AudioMillis
+	usageLog.Characters = h.Units.Characters
+	usageLog.Estimated = h.Estimated
+	if h.Stream.Stream {
+		usageLog.Stream = true
+		usageLog.FirstTokenTime = h.Stream.FirstToken.Milliseconds()
+		usageLog.StreamDuration = h.Stream.Duration.Milliseconds()
+		usageLog.TokensPerSecond = h.Stream.TokensPerSecond(h.OutputTokens)
+		usageLog.Disconnected = h.Stream.Disconnected
+	}
+	h.usageMutex.Unlock()
+
+	user.AddUsageLog(h.ApiKey, h.ApiKeyInfo, usageLog)
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"apiserver/client/openserver"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询密钥所属工作空间的使用量，用于只读密钥
+
+type UsageSummaryHandler struct {
+	Handler
+}
+
+func NewUsageSummaryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &UsageSummaryHandler{}
+		h.OnSummary(c)
+	}
+}
+
+func (h *UsageSummaryHandler) OnSummary(c *gin.Context) {
+	h.GinContext = c
+
+	if err := h.checkApiKey(); err != nil {
+		c.AbortWithStatusJSON(err.Data.Code, err)
+		return
+	}
+
+	pageIndex, _ := strconv.Atoi(c.Query("pageIndex"))
+	pageSize, _ := strconv.Atoi(c.Query("pageSize"))
+	request := &openserver.UsageSummaryRequest{
+		ID:        h.ApiKey,
+		GroupBy:   c.Query("groupBy"),
+		ModelName: c.Query("modelName"),
+		StartTime: c.Query("startTime"),
+		EndTime:   c.Query("endTime"),
+		PageIndex: pageIndex,
+		PageSize:  pageSize,
+	}
+
+	resp, err := openserver.UsageSummary(h.GetRequestContext(), request)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadGateway, NewResponseError(http.StatusBadGateway, err.Error()))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", resp)
+}
@@ -1,6 +1,10 @@
 package rest
 
 import (
+	"apiserver/client/openserver"
+	"apiserver/snapshot"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -8,6 +12,18 @@ type HealthHandler struct {
 	Handler[any]
 }
 
+// 开放平台不可达时为降级状态，网关仍使用缓存和快照继续服务
+type HealthResponse struct {
+	Status     string     `json:"status"`               // ok 或 degraded
+	LastSync   *time.Time `json:"lastSync,omitempty"`   // 最近一次访问开放平台成功的时间
+	SnapshotAt *time.Time `json:"snapshotAt,omitempty"` // 最近一次保存或恢复的快照时间
+}
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
 func NewHealthHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := &HealthHandler{}
@@ -17,4 +33,20 @@ func NewHealthHandler() gin.HandlerFunc {
 }
 
 func (h *HealthHandler) Handle() {
+	response := HealthResponse{Status: HealthOK}
+
+	lastSync := openserver.LastSuccess()
+	if lastSync.IsZero() || openserver.Unreachable() {
+		response.Status = HealthDegraded
+	}
+
+	if !lastSync.IsZero() {
+		response.LastSync = &lastSync
+	}
+
+	if snapshotAt := snapshot.SavedAt(); !snapshotAt.IsZero() {
+		response.SnapshotAt = &snapshotAt
+	}
+
+	h.SetResponseData(response)
 }
@@ -0,0 +1,153 @@
+package snapshot
+
+import (
+	"apiserver/client/openserver"
+	"apiserver/config"
+	"apiserver/model"
+	"apiserver/user"
+	"common/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// 最近一次成功从开放平台加载的状态，开放平台不可达时继续服务
+type Snapshot struct {
+	SavedAt    time.Time                              `json:"savedAt"`
+	Services   []openserver.ModelServicesResponse     `json:"services"`
+	ModelInfos []openserver.ModelInfoResponse         `json:"modelInfos"`
+	Aliases    []openserver.ModelAliasResponse        `json:"aliases,omitempty"`
+	Splits     []openserver.ModelSplitResponse        `json:"splits,omitempty"`
+	Keys       map[string]*openserver.KeyInfoResponse `json:"keys"` // 按密钥的SHA-256索引
+}
+
+// 最近一次保存或恢复的快照时间(毫秒)
+var savedAt atomic.Int64
+
+// 启动时恢复快照，文件不存在或已过期时忽略
+func Load() {
+	cfg := config.GetSnapshot()
+	if cfg.Path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Read snapshot failed", logger.Err(err))
+		}
+		return
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		logger.Warn("Parse snapshot failed", logger.Err(err))
+		return
+	}
+
+	if time.Since(snapshot.SavedAt) > cfg.MaxAge {
+		logger.Warn("Snapshot expired, ignored", logger.String("savedAt", snapshot.SavedAt.Format(time.RFC3339)))
+		return
+	}
+
+	model.Restore(&model.SnapshotData{
+		Services: snapshot.Services,
+		Infos:    snapshot.ModelInfos,
+		Aliases:  snapshot.Aliases,
+		Splits:   snapshot.Splits,
+	})
+	user.RestoreKeys(snapshot.Keys)
+	savedAt.Store(snapshot.SavedAt.UnixMilli())
+
+	logger.Info("Snapshot restored",
+		logger.String("savedAt", snapshot.SavedAt.Format(time.RFC3339)),
+		logger.Int("services", len(snapshot.Services)),
+		logger.Int("keys", len(snapshot.Keys)))
+}
+
+// 保存快照，开放平台不可达时不覆盖旧快照
+func Save() error {
+	cfg := config.GetSnapshot()
+	if cfg.Path == "" {
+		return nil
+	}
+
+	if openserver.Unreachable() || openserver.LastSuccess().IsZero() {
+		return nil
+	}
+
+	models := model.Snapshot()
+	if models.Services == nil {
+		return nil
+	}
+
+	snapshot := Snapshot{
+		SavedAt:    time.Now(),
+		Services:   models.Services,
+		ModelInfos: models.Infos,
+		Aliases:    models.Aliases,
+		Splits:     models.Splits,
+		Keys:       user.SnapshotKeys(),
+	}
+
+	data, err := json.Marshal(&snapshot)
+	if err != nil {
+		return err
+	}
+
+	// 先写临时文件再替换，避免中途退出留下不完整的快照
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := cfg.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, cfg.Path); err != nil {
+		return err
+	}
+
+	savedAt.Store(snapshot.SavedAt.UnixMilli())
+	return nil
+}
+
+// 定时保存快照任务
+func SaveTask(ctx context.Context) {
+	cfg := config.GetSnapshot()
+	if cfg.Path == "" {
+		return
+	}
+
+	logger.Info("Snapshot background task start")
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := Save(); err != nil {
+				logger.Error("Save snapshot", logger.Err(err))
+			}
+		case <-ctx.Done():
+			goto end
+		}
+	}
+
+end:
+	logger.Info("Snapshot background task final")
+}
+
+// 最近一次保存或恢复的快照时间，没有快照时为零值
+func SavedAt() time.Time {
+	if ms := savedAt.Load(); ms > 0 {
+		return time.UnixMilli(ms)
+	}
+	return time.Time{}
+}
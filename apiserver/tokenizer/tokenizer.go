@@ -0,0 +1,183 @@
+package tokenizer
+
+import (
+	"apiserver/config"
+	"bytes"
+	"common/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// 不支持 /tokenize 的模型，在此期间直接估算
+const unsupportedTTL = 10 * time.Minute
+
+var (
+	client = &http.Client{}
+
+	mutex       sync.Mutex
+	unsupported = make(map[string]time.Time)
+)
+
+type tokenizeResponse struct {
+	Count int `json:"count"`
+}
+
+// 统计文本的token数量，返回是否为估算值
+func CountText(ctx context.Context, baseURL, modelName, text string) (int, bool) {
+	if text == "" {
+		return 0, false
+	}
+
+	request := map[string]any{"model": modelName, "prompt": text, "add_special_tokens": false}
+	if count, ok := tokenize(ctx, baseURL, modelName, request); ok {
+		return count, false
+	}
+
+	return Estimate(text), true
+}
+
+// 统计对话消息的token数量，包含对话模板，返回是否为估算值
+func CountMessages(ctx context.Context, baseURL, modelName string, messages any) (int, bool) {
+	if messages == nil {
+		return 0, false
+	}
+
+	request := map[string]any{"model": modelName, "messages": messages}
+	if count, ok := tokenize(ctx, baseURL, modelName, request); ok {
+		return count, false
+	}
+
+	return Estimate(MessagesText(messages)), true
+}
+
+// 按字符估算token数量：中日韩文字每字约一个token，其他字符约四个一个token
+func Estimate(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// 提取对话消息中的文本
+func MessagesText(messages any) string {
+	list, ok := messages.([]any)
+	if !ok {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, item := range list {
+		message, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		switch content := message["content"].(type) {
+		case string:
+			buf.WriteString(content)
+			buf.WriteByte('\n')
+		case []any:
+			// 多模态消息只统计文本部分
+			for _, part := range content {
+				if part, ok := part.(map[string]any); ok {
+					if text, ok := part["text"].(string); ok {
+						buf.WriteString(text)
+						buf.WriteByte('\n')
+					}
+				}
+			}
+		}
+	}
+	return buf.String()
+}
+
+// 调用推理引擎计数
+func tokenize(ctx context.Context, baseURL, modelName string, request any) (int, bool) {
+	cfg := config.GetTokenizer()
+	if cfg.Mode != config.TokenizerEngine || isUnsupported(modelName) {
+		return 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	count, err := doTokenize(ctx, baseURL, request)
+	if err != nil {
+		logger.Warn("Tokenize", logger.String("model", modelName), logger.Err(err))
+		if err == errUnsupported {
+			setUnsupported(modelName)
+		}
+		return 0, false
+	}
+
+	return count, true
+}
+
+var errUnsupported = errors.New("tokenize not supported")
+
+func doTokenize(ctx context.Context, baseURL string, request any) (int, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/tokenize", bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return 0, errUnsupported
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("tokenize status %d", resp.StatusCode)
+	}
+
+	var result tokenizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+
+	return result.Count, nil
+}
+
+func isUnsupported(modelName string) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	until, ok := unsupported[modelName]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(until) {
+		delete(unsupported, modelName)
+		return false
+	}
+	return true
+}
+
+func setUnsupported(modelName string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	unsupported[modelName] = time.Now().Add(unsupportedTTL)
+}
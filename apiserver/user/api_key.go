@@ -1,38 +1,93 @@
 package user
 
-import "time"
-
-type ApiKeys map[string]*ApiKeyInfo
+import (
+	"apiserver/client/openserver"
+	"common/logger"
+	"common/types"
+	"net/netip"
+	"slices"
+	"time"
+)
 
 type ApiKeyInfo struct {
-	WorkspaceInfo *WorkspaceInfo // 可能为空
-	ExpiresAt     *time.Time     // 到期时间
+	ID            string                // 密钥的查找哈希
+	UserID        string                // 用户ID
+	WorkspaceInfo *WorkspaceInfo        // 可能为空
+	UserLimit     *openserver.UserLimit // 用户汇总调用限制，为空时不限制
+	ExpiresAt     *time.Time            // 到期时间，为空时永不过期
+	Balance       *types.Amount         // 预付费余额，为空时不限制
+	Scopes        []string              // 允许调用的接口，为空时不限制
+	Models        []string              // 允许调用的模型，为空时不限制
+	AllowedIPs    []netip.Prefix        // 允许的来源地址，为空时不限制
+	RequestLimit  int64                 // 密钥请求数限流（次/分钟）
+	TokenLimit    int64                 // 密钥Token限流（Tokens/分钟）
+
+	source *openserver.KeyInfoResponse // 开放平台返回的原始信息，用于保存快照
 }
 
-func (keys ApiKeys) Find(id string) *ApiKeyInfo {
-	info := keys[id]
-	if info == nil {
-		return nil
+func newApiKeyInfo(resp *openserver.KeyInfoResponse) *ApiKeyInfo {
+	info := &ApiKeyInfo{
+		ID:            resp.ID,
+		UserID:        resp.UserID,
+		WorkspaceInfo: &WorkspaceInfo{ID: resp.WorkspaceID, Tier: resp.Tier, UsageLimits: resp.UsageLimits},
+		ExpiresAt:     resp.ExpiresAt,
+		Balance:       resp.Balance,
+		UserLimit:     resp.UserLimit,
+		source:        resp,
 	}
+	info.SetRestriction(&resp.ApiKeyRestriction)
+	return info
+}
 
-	if info.ExpiresAt.Before(time.Now()) {
-		delete(keys, id)
-		return nil
+// 设置密钥限制，无法解析的地址忽略
+func (info *ApiKeyInfo) SetRestriction(restriction *openserver.ApiKeyRestriction) {
+	info.Scopes = restriction.Scopes
+	info.Models = restriction.Models
+	info.RequestLimit = restriction.RequestLimit
+	info.TokenLimit = restriction.TokenLimit
+	for _, allowed := range restriction.AllowedIPs {
+		prefix, err := netip.ParsePrefix(allowed)
+		if err != nil {
+			logger.Warn("Invalid allowed ip", logger.String("ip", allowed))
+			continue
+		}
+		info.AllowedIPs = append(info.AllowedIPs, prefix)
 	}
+}
 
-	return info
+// 是否允许调用接口
+func (info *ApiKeyInfo) AllowScope(scope string) bool {
+	return len(info.Scopes) == 0 || slices.Contains(info.Scopes, scope)
 }
 
-func (keys ApiKeys) Add(id string, info *ApiKeyInfo) {
-	expiredAt := time.Now().Add(time.Second * 180)
-	if info.ExpiresAt == nil {
-		info.ExpiresAt = &expiredAt
-	} else if info.ExpiresAt.After(expiredAt) {
-		info.ExpiresAt = &expiredAt
+// 是否允许调用模型
+func (info *ApiKeyInfo) AllowModel(modelName string) bool {
+	return len(info.Models) == 0 || slices.Contains(info.Models, modelName)
+}
+
+// 是否允许来源地址
+func (info *ApiKeyInfo) AllowIP(ip string) bool {
+	if len(info.AllowedIPs) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
 	}
-	keys[id] = info
+	addr = addr.Unmap()
+
+	return slices.ContainsFunc(info.AllowedIPs, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
+
+// 是否已过期
+func (info *ApiKeyInfo) Expired() bool {
+	return info.ExpiresAt != nil && info.ExpiresAt.Before(time.Now())
 }
 
-func (keys ApiKeys) Del(id string) {
-	delete(keys, id)
+// 余额是否耗尽
+func (info *ApiKeyInfo) BalanceExhausted() bool {
+	return info.Balance != nil && info.Balance.Sign() <= 0
 }
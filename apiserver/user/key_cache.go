@@ -0,0 +1,154 @@
+package user
+
+import (
+	"container/list"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 分片数量，减少锁竞争
+const keyCacheShards = 16
+
+type keyEntry struct {
+	id         string
+	info       *ApiKeyInfo // 为空表示无效密钥
+	expiresAt  time.Time
+	staleUntil time.Time // 过期后仍可返回旧数据的截止时间
+}
+
+type keyShard struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List // 最近使用的在前
+}
+
+// 分片LRU密钥缓存，同时缓存无效密钥
+type KeyCache struct {
+	shards     [keyCacheShards]*keyShard
+	generation atomic.Uint64 // 每次清理后递增，加载期间发生清理时不缓存加载结果
+}
+
+func NewKeyCache(size int) *KeyCache {
+	c := &KeyCache{}
+	capacity := max(size/keyCacheShards, 1)
+	for i := range c.shards {
+		c.shards[i] = &keyShard{
+			capacity: capacity,
+			entries:  make(map[string]*list.Element),
+			lru:      list.New(),
+		}
+	}
+	return c
+}
+
+func (c *KeyCache) shard(id string) *keyShard {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return c.shards[h.Sum32()%keyCacheShards]
+}
+
+// 查找密钥，found 为 true 且 info 为空表示无效密钥，stale 表示已过期但仍可作为旧数据使用
+func (c *KeyCache) Get(id string) (info *ApiKeyInfo, found, stale bool) {
+	s := c.shard(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	elem := s.entries[id]
+	if elem == nil {
+		return nil, false, false
+	}
+
+	entry := elem.Value.(*keyEntry)
+	now := time.Now()
+	if now.After(entry.staleUntil) {
+		s.lru.Remove(elem)
+		delete(s.entries, id)
+		return nil, false, false
+	}
+
+	s.lru.MoveToFront(elem)
+	return entry.info, true, now.After(entry.expiresAt)
+}
+
+// 缓存密钥，info 为空表示无效密钥，过期后在 staleTTL 内仍可作为旧数据返回
+func (c *KeyCache) Set(id string, info *ApiKeyInfo, ttl, staleTTL time.Duration) {
+	s := c.shard(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	entry := &keyEntry{id: id, info: info, expiresAt: now.Add(ttl), staleUntil: now.Add(ttl + staleTTL)}
+	if elem := s.entries[id]; elem != nil {
+		elem.Value = entry
+		s.lru.MoveToFront(elem)
+		return
+	}
+
+	s.entries[id] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.capacity {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*keyEntry).id)
+	}
+}
+
+// 遍历有效密钥，用于保存快照
+func (c *KeyCache) Range(fn func(id string, info *ApiKeyInfo)) {
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		for id, elem := range s.entries {
+			if info := elem.Value.(*keyEntry).info; info != nil {
+				fn(id, info)
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// 当前清理版本
+func (c *KeyCache) Generation() uint64 {
+	return c.generation.Load()
+}
+
+// 删除满足条件的密钥，无效密钥传入空值，返回删除数量
+func (c *KeyCache) DelFunc(match func(info *ApiKeyInfo) bool) int {
+	c.generation.Add(1)
+	count := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		for id, elem := range s.entries {
+			if match(elem.Value.(*keyEntry).info) {
+				s.lru.Remove(elem)
+				delete(s.entries, id)
+				count++
+			}
+		}
+		s.mutex.Unlock()
+	}
+	return count
+}
+
+// 清空缓存
+func (c *KeyCache) Clear() {
+	c.generation.Add(1)
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		s.entries = make(map[string]*list.Element)
+		s.lru.Init()
+		s.mutex.Unlock()
+	}
+}
+
+// 缓存的密钥数量
+func (c *KeyCache) Len() int {
+	count := 0
+	for _, s := range c.shards {
+		s.mutex.Lock()
+		count += s.lru.Len()
+		s.mutex.Unlock()
+	}
+	return count
+}
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"sync"
+	"time"
+)
+
+// 最多跟踪的来源地址数量，超出时不再记录新地址
+const maxFailureTracked = 100000
+
+type failureCounter struct {
+	count     int
+	windowEnd time.Time
+}
+
+// 按来源地址统计认证失败次数，超出限制后在窗口结束前拒绝
+type FailureThrottle struct {
+	mutex    sync.Mutex
+	limit    int
+	window   time.Duration
+	counters map[string]*failureCounter
+}
+
+func NewFailureThrottle(limit int, window time.Duration) *FailureThrottle {
+	return &FailureThrottle{
+		limit:    limit,
+		window:   window,
+		counters: make(map[string]*failureCounter),
+	}
+}
+
+// 地址是否被拒绝，返回需要等待的时间
+func (t *FailureThrottle) Blocked(ip string) (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	counter := t.counters[ip]
+	if counter == nil {
+		return 0, false
+	}
+
+	wait := time.Until(counter.windowEnd)
+	if wait <= 0 {
+		delete(t.counters, ip)
+		return 0, false
+	}
+
+	return wait, counter.count >= t.limit
+}
+
+// 记录一次认证失败
+func (t *FailureThrottle) Fail(ip string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	counter := t.counters[ip]
+	if counter == nil || now.After(counter.windowEnd) {
+		if counter == nil && len(t.counters) >= maxFailureTracked {
+			t.cleanup(now)
+			if len(t.counters) >= maxFailureTracked {
+				return
+			}
+		}
+		counter = &failureCounter{windowEnd: now.Add(t.window)}
+		t.counters[ip] = counter
+	}
+
+	counter.count++
+}
+
+func (t *FailureThrottle) cleanup(now time.Time) {
+	for ip, counter := range t.counters {
+		if now.After(counter.windowEnd) {
+			delete(t.counters, ip)
+		}
+	}
+}
@@ -1,12 +1,40 @@
 package user
 
+import (
+	"apiserver/client/openserver"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"time"
+)
+
 type UsageLog struct {
 	Timestampt   int64
+	ModelName    string
 	ServiceID    string
+	Target       string // 最终处理请求的副本地址
+	Attempts     int    // 转发尝试次数
 	Status       UsageStatus
 	InputTokens  int64
 	OutputTokens int64
+	CachedTokens int64
 	ResponseTime int64
+	ClientIP     string // 调用的来源地址
+
+	// 非token计费单位
+	ImageCount  int64 // 生成图片数量
+	ImagePixels int64 // 生成图片总像素
+	AudioMillis int64 // 音频时长(毫秒)
+	Characters  int64 // 语音合成的字符数
+	Estimated   bool  // 使用量由网关统计
+
+	// 流式响应统计
+	Stream          bool
+	FirstTokenTime  int64   // 首个内容块耗时(毫秒)
+	StreamDuration  int64   // 流式响应总耗时(毫秒)
+	TokensPerSecond float64 // 输出速度
+	Disconnected    bool    // 客户端中途断开
 }
 
 type UsageStatus int
@@ -16,26 +44,176 @@ const (
 	UsageFailed  UsageStatus = 1
 )
 
+// 待上报日志数量上限，上报持续失败时丢弃最旧的日志
+const MaxPendingUsageLogs = 100000
+
+// 待上报批次的最长保留时长，开放平台保留批次ID的时长需大于此值
+const MaxPendingUsageAge = 24 * time.Hour
+
 type UsageLogs map[string]*UsageLogInfo
 
 type UsageLogInfo struct {
-	UsageLogs []*UsageLog
+	UserID      string
+	WorkspaceID string
+	LastUsedIP  string // 最近调用的来源地址
+	UsageLogs   []*UsageLog
 }
 
-func (u UsageLogs) Add(keyID string, usageLog *UsageLog) {
+func (u UsageLogs) Add(keyID string, keyInfo *ApiKeyInfo, usageLog *UsageLog) {
 	found := u[keyID]
 	if found == nil {
-		found = &UsageLogInfo{}
+		found = &UsageLogInfo{UserID: keyInfo.UserID}
+		if keyInfo.WorkspaceInfo != nil {
+			found.WorkspaceID = keyInfo.WorkspaceInfo.ID
+		}
+		u[keyID] = found
 	}
 
+	if usageLog.ClientIP != "" {
+		found.LastUsedIP = usageLog.ClientIP
+	}
 	found.UsageLogs = append(found.UsageLogs, usageLog)
 }
 
-func (u UsageLogs) Clone() UsageLogs {
-	usageLogs = make(UsageLogs)
-	for k, v := range u {
-		usageLog := *v
-		usageLogs[k] = &usageLog
+func (u UsageLogs) Count() int {
+	count := 0
+	for _, info := range u {
+		count += len(info.UsageLogs)
+	}
+	return count
+}
+
+// 转换为上报请求
+func (u UsageLogs) ToReport() []openserver.KeyUsageLogs {
+	var report []openserver.KeyUsageLogs
+	for keyID, info := range u {
+		keyUsageLogs := openserver.KeyUsageLogs{
+			ApiKey:      keyID,
+			UserID:      info.UserID,
+			WorkspaceID: info.WorkspaceID,
+			LastUsedIP:  info.LastUsedIP,
+		}
+
+		for _, usageLog := range info.UsageLogs {
+			keyUsageLogs.UsageLogs = append(keyUsageLogs.UsageLogs, openserver.UsageLog{
+				Timestamp:    usageLog.Timestampt,
+				ModelName:    usageLog.ModelName,
+				ServiceID:    usageLog.ServiceID,
+				Target:       usageLog.Target,
+				Attempts:     usageLog.Attempts,
+				Status:       int(usageLog.Status),
+				InputTokens:  usageLog.InputTokens,
+				OutputTokens: usageLog.OutputTokens,
+				CachedTokens: usageLog.CachedTokens,
+				ResponseTime: usageLog.ResponseTime,
+
+				ImageCount:  usageLog.ImageCount,
+				ImagePixels: usageLog.ImagePixels,
+				AudioMillis: usageLog.AudioMillis,
+				Characters:  usageLog.Characters,
+				Estimated:   usageLog.Estimated,
+
+				Stream:          usageLog.Stream,
+				FirstTokenTime:  usageLog.FirstTokenTime,
+				StreamDuration:  usageLog.StreamDuration,
+				TokensPerSecond: usageLog.TokensPerSecond,
+				Disconnected:    usageLog.Disconnected,
+			})
+		}
+
+		report = append(report, keyUsageLogs)
+	}
+	return report
+}
+
+// 丢弃最旧的日志，直到数量不超过上限
+// 同一密钥的日志按时间追加，一次遍历即可按截止时间丢弃
+func (u UsageLogs) Trim(limit int) int {
+	count := u.Count()
+	excess := count - limit
+	if excess <= 0 {
+		return 0
+	}
+
+	timestamps := make([]int64, 0, count)
+	for _, info := range u {
+		for _, usageLog := range info.UsageLogs {
+			timestamps = append(timestamps, usageLog.Timestampt)
+		}
+	}
+	slices.Sort(timestamps)
+
+	// 早于截止时间的全部丢弃，等于截止时间的丢弃剩余数量
+	cutoff := timestamps[excess-1]
+	equal := excess - sort.Search(len(timestamps), func(i int) bool { return timestamps[i] >= cutoff })
+
+	dropped := 0
+	for keyID, info := range u {
+		kept := info.UsageLogs[:0]
+		for _, usageLog := range info.UsageLogs {
+			if usageLog.Timestampt < cutoff || (usageLog.Timestampt == cutoff && equal > 0) {
+				if usageLog.Timestampt == cutoff {
+					equal--
+				}
+				dropped++
+				continue
+			}
+			kept = append(kept, usageLog)
+		}
+
+		if len(kept) == 0 {
+			delete(u, keyID)
+		} else {
+			info.UsageLogs = kept
+		}
+	}
+	return dropped
+}
+
+// 一次上报的日志，重试时沿用批次ID，开放平台据此去重，避免重复记录和扣费
+type UsageBatch struct {
+	ID        string
+	Logs      UsageLogs
+	Count     int
+	CreatedAt time.Time
+}
+
+func NewUsageBatch(usageLogs UsageLogs) *UsageBatch {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &UsageBatch{ID: hex.EncodeToString(id), Logs: usageLogs, Count: usageLogs.Count(), CreatedAt: time.Now()}
+}
+
+// 丢弃超过保留时长的批次，返回丢弃的日志数量
+func ExpireUsageBatches(batches []*UsageBatch, maxAge time.Duration) ([]*UsageBatch, int) {
+	dropped := 0
+	for len(batches) > 0 && time.Since(batches[0].CreatedAt) > maxAge {
+		dropped += batches[0].Count
+		batches = batches[1:]
 	}
-	return usageLogs
+	return batches, dropped
+}
+
+// 丢弃最旧的批次，直到待上报日志数量不超过上限，仅剩一个批次时丢弃其中最旧的日志
+func TrimUsageBatches(batches []*UsageBatch, limit int) ([]*UsageBatch, int) {
+	total := 0
+	for _, batch := range batches {
+		total += batch.Count
+	}
+
+	dropped := 0
+	for total > limit && len(batches) > 1 {
+		total -= batches[0].Count
+		dropped += batches[0].Count
+		batches = batches[1:]
+	}
+
+	if total > limit && len(batches) == 1 {
+		batch := batches[0]
+		n := batch.Logs.Trim(limit)
+		batch.Count -= n
+		dropped += n
+	}
+
+	return batches, dropped
 }
@@ -2,69 +2,311 @@ package user
 
 import (
 	"apiserver/client/openserver"
+	"apiserver/config"
+	"apiserver/metrics"
 	"common"
+	"common/logger"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 var (
 	mutex     sync.Mutex
-	apiKeys   ApiKeys
 	usageLogs UsageLogs
+
+	reportMutex    sync.Mutex
+	pendingBatches []*UsageBatch // 上报失败的批次，按时间顺序重试
+
+	apiKeys      *KeyCache
+	keyLoader    singleflight.Group
+	refreshing   sync.Map // 正在后台刷新的密钥
+	authFailures *FailureThrottle
+
+	knownMutex sync.RWMutex
+	knownKeys  map[string]*openserver.KeyInfoResponse // 快照中的密钥
 )
 
+// 密钥无效、已停用或已过期
+var ErrKeyNotFound = errors.New("invalid API KEY")
+
 func init() {
-	apiKeys = make(ApiKeys)
 	usageLogs = make(UsageLogs)
 }
 
-// 查找API密钥
+// 按配置初始化密钥缓存
+func Init() {
+	cfg := config.GetKeyCache()
+	apiKeys = NewKeyCache(cfg.Size)
+	authFailures = NewFailureThrottle(cfg.FailureLimit, cfg.FailureWindow)
+}
+
+// 查找API密钥，无效的密钥返回 ErrKeyNotFound
 func FindKey(ctx context.Context, id string) (*ApiKeyInfo, error) {
-	mutex.Lock()
-	found := apiKeys.Find(id)
-	if found == nil {
-		mutex.Unlock()
-		resp, err := openserver.FindApiKey(ctx, id)
-		if err != nil {
-			if !common.IsErrorCode(err, common.ApiKeyNotFound) {
-				return nil, err
+	if info, found, stale := apiKeys.Get(id); found {
+		if info == nil {
+			metrics.KeyCacheNegative()
+			return nil, ErrKeyNotFound
+		}
+
+		if !stale {
+			metrics.KeyCacheHit()
+			return info, nil
+		}
+
+		// 缓存已过期，先返回旧数据再后台刷新，同一密钥同时只启动一个刷新
+		metrics.KeyCacheStale()
+		if _, loaded := refreshing.LoadOrStore(id, struct{}{}); !loaded {
+			go refreshKey(id)
+		}
+		return info, nil
+	}
+
+	metrics.KeyCacheMiss()
+
+	// 同一密钥同时只查询一次，不受发起请求的客户端断开影响
+	v, err, _ := keyLoader.Do(id, func() (any, error) {
+		return loadKey(context.WithoutCancel(ctx), id)
+	})
+	if err == nil {
+		return v.(*ApiKeyInfo), nil
+	}
+
+	// 开放平台不可达时使用快照中的密钥
+	if !errors.Is(err, ErrKeyNotFound) {
+		if info := findKnownKey(id); info != nil {
+			metrics.KeyCacheSnapshot()
+			return info, nil
+		}
+	}
+
+	return nil, err
+}
+
+// 后台刷新过期的密钥，失败时保留旧数据
+func refreshKey(id string) {
+	defer refreshing.Delete(id)
+
+	keyLoader.Do(id, func() (any, error) {
+		info, err := loadKey(context.Background(), id)
+		if err != nil && !errors.Is(err, ErrKeyNotFound) {
+			logger.Warn("Refresh api key failed", logger.Err(err))
+		}
+		return info, err
+	})
+}
+
+func loadKey(ctx context.Context, id string) (*ApiKeyInfo, error) {
+	cfg := config.GetKeyCache()
+	generation := apiKeys.Generation()
+
+	resp, err := openserver.FindApiKey(ctx, id)
+	if err != nil {
+		// 不存在或已停用的密钥短时缓存，避免反复查询
+		if common.IsErrorCode(err, common.ApiKeyNotFound) || common.IsErrorCode(err, common.ApiKeyDisabled) {
+			if apiKeys.Generation() == generation {
+				apiKeys.Set(id, nil, cfg.NegativeTTL, 0)
 			}
+			return nil, ErrKeyNotFound
 		}
+		return nil, err
+	}
 
-		found = &ApiKeyInfo{}
-		if resp != nil {
-			found.WorkspaceInfo = &WorkspaceInfo{UsageLimits: resp.UsageLimits}
-			found.ExpiresAt = resp.ExpiresAt
+	info := newApiKeyInfo(resp)
+	if info.Expired() {
+		if apiKeys.Generation() == generation {
+			apiKeys.Set(id, nil, cfg.NegativeTTL, 0)
 		}
+		return nil, ErrKeyNotFound
+	}
 
-		mutex.Lock()
-		apiKeys.Add(id, found)
+	// 加载期间缓存被清理时不缓存
+	if apiKeys.Generation() == generation {
+		cacheKey(id, info)
 	}
 
-	defer mutex.Unlock()
+	return info, nil
+}
+
+// 缓存有效密钥，缓存和旧数据均不超过密钥的到期时间
+func cacheKey(id string, info *ApiKeyInfo) {
+	cfg := config.GetKeyCache()
+	ttl, staleTTL := cfg.TTL, cfg.StaleTTL
+	if info.ExpiresAt != nil {
+		remaining := time.Until(*info.ExpiresAt)
+		ttl = min(ttl, remaining)
+		staleTTL = min(staleTTL, remaining-ttl)
+	}
+	apiKeys.Set(id, info, ttl, staleTTL)
+}
+
+// 快照中的密钥按密钥的SHA-256索引，密钥本身不落盘
+func keyDigest(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// 查找快照中的密钥，找到后放入缓存
+func findKnownKey(id string) *ApiKeyInfo {
+	knownMutex.RLock()
+	resp := knownKeys[keyDigest(id)]
+	knownMutex.RUnlock()
 
-	if found.WorkspaceInfo == nil {
-		return nil, nil
+	if resp == nil {
+		return nil
 	}
 
-	return found, nil
+	info := newApiKeyInfo(resp)
+	if info.Expired() {
+		return nil
+	}
+
+	cacheKey(id, info)
+	return info
+}
+
+// 恢复快照中的密钥，仅在开放平台不可达时使用
+func RestoreKeys(keys map[string]*openserver.KeyInfoResponse) {
+	knownMutex.Lock()
+	defer knownMutex.Unlock()
+	knownKeys = keys
+}
+
+// 缓存中的有效密钥，用于保存快照
+func SnapshotKeys() map[string]*openserver.KeyInfoResponse {
+	keys := make(map[string]*openserver.KeyInfoResponse)
+	apiKeys.Range(func(id string, info *ApiKeyInfo) {
+		if info.source != nil {
+			keys[keyDigest(id)] = info.source
+		}
+	})
+	return keys
+}
+
+// 来源地址认证失败过多时返回需要等待的时间
+func AuthBlocked(ip string) (time.Duration, bool) {
+	return authFailures.Blocked(ip)
+}
+
+// 记录来源地址的一次认证失败
+func AuthFailed(ip string) {
+	authFailures.Fail(ip)
+}
+
+// 按变更事件清理密钥缓存，返回清理数量
+func InvalidateKeys(kind, target string) int {
+	switch kind {
+	case openserver.ChangeKey:
+		// 无效密钥的缓存没有查找哈希，启用密钥后一并清理
+		return apiKeys.DelFunc(func(info *ApiKeyInfo) bool {
+			return info == nil || info.ID == target
+		})
+	case openserver.ChangeWorkspace:
+		return apiKeys.DelFunc(func(info *ApiKeyInfo) bool {
+			return info != nil && info.WorkspaceInfo.ID == target
+		})
+	case openserver.ChangeUser:
+		return apiKeys.DelFunc(func(info *ApiKeyInfo) bool {
+			return info != nil && info.UserID == target
+		})
+	}
+
+	return 0
+}
+
+// 清空密钥缓存
+func InvalidateAllKeys() {
+	apiKeys.Clear()
 }
 
 // 记录使用量
-func AddUsageLog(keyID string, usageLog *UsageLog) {
+func AddUsageLog(keyID string, keyInfo *ApiKeyInfo, usageLog *UsageLog) {
 	usageLog.Timestampt = time.Now().UnixMilli()
 	mutex.Lock()
 	defer mutex.Unlock()
-	usageLogs.Add(keyID, usageLog)
+	usageLogs.Add(keyID, keyInfo, usageLog)
 }
 
 // 报告使用量
-func ReportUsageLog() error {
+// 新日志作为一个批次追加到待上报队列，按顺序上报，可重试的失败保留原批次ID等待下次重试，被拒绝的批次丢弃
+func ReportUsageLog(ctx context.Context) error {
+	reportMutex.Lock()
+	defer reportMutex.Unlock()
+
 	mutex.Lock()
-	// report := usageLogs
-	usageLogs = make(UsageLogs)
+	if len(usageLogs) > 0 {
+		pendingBatches = append(pendingBatches, NewUsageBatch(usageLogs))
+		usageLogs = make(UsageLogs)
+	}
 	mutex.Unlock()
 
+	var dropped int
+	if pendingBatches, dropped = ExpireUsageBatches(pendingBatches, MaxPendingUsageAge); dropped > 0 {
+		logger.Warn("Expired usage logs dropped", logger.Int("count", dropped))
+	}
+
+	for len(pendingBatches) > 0 {
+		batch := pendingBatches[0]
+		if err := openserver.ReportUsageLogs(ctx, batch.ID, batch.Logs.ToReport()); err != nil {
+			// 开放平台拒绝的批次重试也不会成功，丢弃后继续上报后续批次
+			if isPermanentReportError(err) {
+				logger.Error("Usage batch rejected", logger.String("batch", batch.ID), logger.Int("count", batch.Count), logger.Err(err))
+				pendingBatches[0] = nil
+				pendingBatches = pendingBatches[1:]
+				continue
+			}
+
+			if pendingBatches, dropped = TrimUsageBatches(pendingBatches, MaxPendingUsageLogs); dropped > 0 {
+				logger.Warn("Usage logs dropped", logger.Int("count", dropped))
+			}
+			return err
+		}
+
+		pendingBatches[0] = nil
+		pendingBatches = pendingBatches[1:]
+	}
+
 	return nil
 }
+
+// 网络错误、非200响应和开放平台内部错误可重试，其他业务错误码表示请求被拒绝
+func isPermanentReportError(err error) bool {
+	var codeErr *common.Error
+	if !errors.As(err, &codeErr) {
+		return false
+	}
+
+	switch codeErr.Code {
+	case common.Failure, common.InnerServerError, common.HandleError, common.InnerAccessError:
+		return false
+	}
+	return true
+}
+
+// 定时上报使用量任务
+func ReportUsageLogTask(ctx context.Context) {
+
+	logger.Info("Usage background task start")
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ReportUsageLog(ctx); err != nil {
+				logger.Error("ReportUsageLog", logger.Err(err))
+			}
+		case <-ctx.Done():
+			goto end
+		}
+	}
+
+end:
+	logger.Info("Usage background task final")
+}
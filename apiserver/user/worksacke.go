@@ -5,6 +5,8 @@ import "apiserver/client/openserver"
 type Workspaces map[string]*WorkspaceInfo
 
 type WorkspaceInfo struct {
+	ID          string
+	Tier        int // 服务等级，排队时等级高的优先
 	UsageLimits []openserver.UsageLimit
 }
 
@@ -15,3 +17,13 @@ func (w Workspaces) Set(id string, info *WorkspaceInfo) {
 func (w Workspaces) Get(id string) *WorkspaceInfo {
 	return w[id]
 }
+
+// 查找模型调用限制
+func (info *WorkspaceInfo) FindUsageLimit(modelName string) *openserver.UsageLimit {
+	for i := range info.UsageLimits {
+		if info.UsageLimits[i].ModelName == modelName {
+			return &info.UsageLimits[i]
+		}
+	}
+	return nil
+}
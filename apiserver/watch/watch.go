@@ -0,0 +1,65 @@
+package watch
+
+import (
+	"apiserver/client/openserver"
+	"apiserver/config"
+	"apiserver/model"
+	"apiserver/user"
+	"common/logger"
+	"context"
+	"time"
+)
+
+// 监听开放平台变更事件任务，及时清理密钥缓存和重新加载模型服务，定时刷新作为兜底
+func WatchTask(ctx context.Context) {
+	cfg := config.GetWatch()
+	if cfg.Disabled {
+		return
+	}
+
+	logger.Info("Watch background task start")
+
+	revision := int64(-1)
+	for ctx.Err() == nil {
+		changeSet, err := openserver.Watch(ctx, revision, cfg.Timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+
+			logger.Warn("Watch changes failed", logger.Err(err))
+			select {
+			case <-time.After(cfg.RetryInterval):
+			case <-ctx.Done():
+			}
+			continue
+		}
+
+		// 首次监听只获取当前版本
+		if revision >= 0 {
+			apply(changeSet)
+		}
+		revision = changeSet.Revision
+	}
+
+	logger.Info("Watch background task final")
+}
+
+func apply(changeSet *openserver.ChangeSet) {
+	if changeSet.Reset {
+		logger.Info("Watch reset, clear all caches", logger.Int64("revision", changeSet.Revision))
+		user.InvalidateAllKeys()
+		model.RequestReload()
+		return
+	}
+
+	for _, event := range changeSet.Events {
+		if event.Kind == openserver.ChangeService {
+			model.RequestReload()
+			continue
+		}
+
+		count := user.InvalidateKeys(event.Kind, event.Target)
+		logger.Debug("Change applied", logger.String("kind", event.Kind), logger.Int("keys", count))
+	}
+}
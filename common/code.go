@@ -1,23 +1,27 @@
 package common
 
 const (
-	Success             int = 0    // 成功
-	Failure             int = 1    // 失败
-	InnerServerError    int = 2    // 内部服务错误
-	RequestDataError    int = 3    // 请求数据错误
-	RequestParamError   int = 4    // 请求参数错误
-	HandleError         int = 5    // 处理请求异常
-	HandlerNotFound     int = 6    // 未找到处理器
-	AuthError           int = 7    // 认证失败
-	InnerAccessError    int = 8    // 内部访问失败
-	UserExistError      int = 1000 // 用户已存在
-	UserCreateError     int = 1001 // 创建用户失败
-	UserNotFound        int = 1002 // 用户不存在
-	WorkspaceCountLimit int = 2000 // 达到最大工作空间数量
-	WorkspaceNotFound   int = 2001 // 未找到工作空间
-	ApiKeyNotFound      int = 3000 // API密钥不存在
-	ApiServiceNotFound  int = 3001 // API网关不存在
-	PlatModelNotFound   int = 4000 // 没有对应的预置模型
+	Success              int = 0    // 成功
+	Failure              int = 1    // 失败
+	InnerServerError     int = 2    // 内部服务错误
+	RequestDataError     int = 3    // 请求数据错误
+	RequestParamError    int = 4    // 请求参数错误
+	HandleError          int = 5    // 处理请求异常
+	HandlerNotFound      int = 6    // 未找到处理器
+	AuthError            int = 7    // 认证失败
+	InnerAccessError     int = 8    // 内部访问失败
+	UserExistError       int = 1000 // 用户已存在
+	UserCreateError      int = 1001 // 创建用户失败
+	UserNotFound         int = 1002 // 用户不存在
+	WorkspaceCountLimit  int = 2000 // 达到最大工作空间数量
+	WorkspaceNotFound    int = 2001 // 未找到工作空间
+	ApiKeyNotFound       int = 3000 // API密钥不存在
+	ApiServiceNotFound   int = 3001 // API网关不存在
+	ApiKeyDisabled       int = 3002 // API密钥已停用
+	PlatModelNotFound    int = 4000 // 没有对应的预置模型
+	ModelPriceNotFound   int = 4001 // 模型价格不存在
+	ModelAliasNotFound   int = 4002 // 模型别名不存在
+	TrafficSplitNotFound int = 4003 // 流量分配规则不存在
 
 )
 
@@ -1,8 +1,11 @@
 package secure
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"strings"
 )
 
@@ -22,3 +25,19 @@ func GenerateApiKey() (string, error) {
 
 	return "sk-" + keyPart, nil
 }
+
+// 计算API密钥的查找哈希，相同密钥得到相同结果，不可还原出密钥
+func HashApiKey(secret, apiKey string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(apiKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// 遮盖API密钥，只保留前缀和末尾几位用于辨认，如 sk-abc…xyz
+func MaskApiKey(apiKey string) string {
+	const head, tail = 6, 3
+	if len(apiKey) <= head+tail {
+		return apiKey[:min(len(apiKey), 3)] + "…"
+	}
+	return apiKey[:head] + "…" + apiKey[len(apiKey)-tail:]
+}
@@ -0,0 +1,223 @@
+package secure
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// 信封加密：每个值使用随机数据密钥加密，数据密钥再由指定版本的主密钥加密后与密文一起保存
+// 密文格式 enc:<主密钥版本>:<加密的数据密钥>:<加密的数据>，轮换主密钥后旧版本密文仍可解密
+
+const envelopePrefix = "enc:"
+
+var ErrKeyVersionNotFound = errors.New("master key version not found")
+
+type KeyringConfig struct {
+	Current  string   `yaml:"current"`  // 加密新数据使用的主密钥版本，为空时不启用
+	Versions []string `yaml:"versions"` // 可用于解密的主密钥版本，轮换期间保留旧版本
+}
+
+func (c *KeyringConfig) Check() error {
+
+	if c.Current == "" {
+		return nil
+	}
+
+	if strings.Contains(c.Current, ":") {
+		return fmt.Errorf("invalid master key version: %s", c.Current)
+	}
+
+	if !slices.Contains(c.Versions, c.Current) {
+		c.Versions = append(c.Versions, c.Current)
+	}
+
+	return nil
+}
+
+// 主密钥在提供者中的名称
+func MasterKeyName(version string) string {
+	return "master-key-" + version
+}
+
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+// 从提供者加载各版本主密钥，主密钥为base64编码的32字节
+func NewKeyring(ctx context.Context, provider KeyProvider, cfg KeyringConfig) (*Keyring, error) {
+	k := &Keyring{current: cfg.Current, keys: make(map[string][]byte)}
+	for _, version := range cfg.Versions {
+		secret, err := provider.GetSecret(ctx, MasterKeyName(version))
+		if err != nil {
+			return nil, fmt.Errorf("load master key %s: %w", version, err)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("master key %s must be 32 bytes in base64", version)
+		}
+
+		k.keys[version] = key
+	}
+
+	return k, nil
+}
+
+// 加密新数据使用的主密钥版本
+func (k *Keyring) Current() string {
+	return k.current
+}
+
+// 是否为信封加密的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// 密文使用的主密钥版本
+func KeyVersion(value string) string {
+	parts := strings.SplitN(strings.TrimPrefix(value, envelopePrefix), ":", 2)
+	return parts[0]
+}
+
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	kek, ok := k.keys[k.current]
+	if !ok {
+		return "", ErrKeyVersionNotFound
+	}
+
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(kek, dek)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := seal(dek, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + k.current + ":" + base64.StdEncoding.EncodeToString(wrapped) + ":" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func (k *Keyring) Decrypt(value string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if !IsEncrypted(value) || len(parts) != 3 {
+		return "", errors.New("invalid envelope ciphertext")
+	}
+
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeyVersionNotFound, parts[0])
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+
+	dek, err := open(kek, wrapped)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dek, data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// 依次解密配置值，非密文原样保留，未配置主密钥时keyring为nil
+func DecryptSecrets(keyring *Keyring, values ...*string) error {
+	for _, value := range values {
+		if !IsEncrypted(*value) {
+			continue
+		}
+
+		if keyring == nil {
+			return errors.New("decrypt secret: master key not configured")
+		}
+
+		plaintext, err := keyring.Decrypt(*value)
+		if err != nil {
+			return fmt.Errorf("decrypt secret: %w", err)
+		}
+		*value = plaintext
+	}
+
+	return nil
+}
+
+// 使用当前主密钥重新加密，已是当前版本时返回false
+func (k *Keyring) Reencrypt(value string) (string, bool, error) {
+	if KeyVersion(value) == k.current {
+		return value, false, nil
+	}
+
+	plaintext, err := k.Decrypt(value)
+	if err != nil {
+		return "", false, err
+	}
+
+	encrypted, err := k.Encrypt(plaintext)
+	if err != nil {
+		return "", false, err
+	}
+
+	return encrypted, true, nil
+}
+
+// AES-256-GCM加密，随机nonce放在密文前
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, data := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, data, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,195 @@
+package secure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 内存中的密钥提供者，按名称保存主密钥
+type mapProvider map[string]string
+
+func (p mapProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func newMasterKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newTestKeyring(t *testing.T, provider KeyProvider, current string, versions ...string) *Keyring {
+	t.Helper()
+	cfg := KeyringConfig{Current: current, Versions: versions}
+	if err := cfg.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := NewKeyring(context.Background(), provider, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	provider := mapProvider{MasterKeyName("v1"): newMasterKey(t)}
+	keyring := newTestKeyring(t, provider, "v1")
+
+	for _, plaintext := range []string{"", "db-password", strings.Repeat("长密钥", 100)} {
+		encrypted, err := keyring.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(encrypted) || KeyVersion(encrypted) != "v1" {
+			t.Fatalf("unexpected ciphertext %q", encrypted)
+		}
+		if plaintext != "" && strings.Contains(encrypted, plaintext) {
+			t.Fatalf("ciphertext contains plaintext")
+		}
+
+		decrypted, err := keyring.Decrypt(encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decrypted != plaintext {
+			t.Fatalf("decrypted %q, want %q", decrypted, plaintext)
+		}
+	}
+
+	// 每次加密使用新的数据密钥和nonce
+	a, _ := keyring.Encrypt("same")
+	b, _ := keyring.Encrypt("same")
+	if a == b {
+		t.Fatal("ciphertexts of the same plaintext are equal")
+	}
+}
+
+func TestEnvelopeWrongKey(t *testing.T) {
+	keyring := newTestKeyring(t, mapProvider{MasterKeyName("v1"): newMasterKey(t)}, "v1")
+	encrypted, err := keyring.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同一版本号但主密钥不同
+	other := newTestKeyring(t, mapProvider{MasterKeyName("v1"): newMasterKey(t)}, "v1")
+	if _, err := other.Decrypt(encrypted); err == nil {
+		t.Fatal("decrypt with wrong master key succeeded")
+	}
+
+	// 缺少密文对应的版本
+	missing := newTestKeyring(t, mapProvider{MasterKeyName("v2"): newMasterKey(t)}, "v2")
+	if _, err := missing.Decrypt(encrypted); !errors.Is(err, ErrKeyVersionNotFound) {
+		t.Fatalf("got %v, want ErrKeyVersionNotFound", err)
+	}
+
+	// 篡改密文
+	parts := strings.Split(encrypted, ":")
+	data, _ := base64.StdEncoding.DecodeString(parts[3])
+	data[len(data)-1] ^= 0xff
+	parts[3] = base64.StdEncoding.EncodeToString(data)
+	if _, err := keyring.Decrypt(strings.Join(parts, ":")); err == nil {
+		t.Fatal("decrypt of tampered ciphertext succeeded")
+	}
+
+	if _, err := other.Decrypt("enc:v1:bad"); err == nil {
+		t.Fatal("decrypt of malformed ciphertext succeeded")
+	}
+}
+
+func TestEnvelopeRotation(t *testing.T) {
+	provider := mapProvider{
+		MasterKeyName("v1"): newMasterKey(t),
+		MasterKeyName("v2"): newMasterKey(t),
+	}
+
+	old, err := newTestKeyring(t, provider, "v1").Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换期间保留旧版本，旧密文仍可解密
+	keyring := newTestKeyring(t, provider, "v2", "v1")
+	if decrypted, err := keyring.Decrypt(old); err != nil || decrypted != "secret" {
+		t.Fatalf("decrypt old version: %q, %v", decrypted, err)
+	}
+
+	rotated, changed, err := keyring.Reencrypt(old)
+	if err != nil || !changed {
+		t.Fatalf("reencrypt: changed=%v, %v", changed, err)
+	}
+	if KeyVersion(rotated) != "v2" {
+		t.Fatalf("rotated version %s, want v2", KeyVersion(rotated))
+	}
+
+	if _, changed, err := keyring.Reencrypt(rotated); err != nil || changed {
+		t.Fatalf("reencrypt current version: changed=%v, %v", changed, err)
+	}
+
+	// 移除旧版本后只能解密新密文
+	current := newTestKeyring(t, mapProvider{MasterKeyName("v2"): provider[MasterKeyName("v2")]}, "v2")
+	if decrypted, err := current.Decrypt(rotated); err != nil || decrypted != "secret" {
+		t.Fatalf("decrypt rotated: %q, %v", decrypted, err)
+	}
+	if _, err := current.Decrypt(old); !errors.Is(err, ErrKeyVersionNotFound) {
+		t.Fatalf("got %v, want ErrKeyVersionNotFound", err)
+	}
+}
+
+func TestKeyringInvalidMasterKey(t *testing.T) {
+	cfg := KeyringConfig{Current: "v1"}
+	if err := cfg.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := NewKeyring(context.Background(), mapProvider{MasterKeyName("v1"): secret}, cfg); err == nil {
+			t.Fatalf("master key %q accepted", secret)
+		}
+	}
+
+	if _, err := NewKeyring(context.Background(), mapProvider{}, cfg); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("got %v, want ErrSecretNotFound", err)
+	}
+
+	if err := (&KeyringConfig{Current: "v:1"}).Check(); err == nil {
+		t.Fatal("version containing ':' accepted")
+	}
+}
+
+func TestDecryptSecrets(t *testing.T) {
+	keyring := newTestKeyring(t, mapProvider{MasterKeyName("v1"): newMasterKey(t)}, "v1")
+
+	password, err := keyring.Encrypt("pa55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := "plain-value"
+	if err := DecryptSecrets(keyring, &password, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if password != "pa55" || plain != "plain-value" {
+		t.Fatalf("decrypted %q %q", password, plain)
+	}
+
+	// 未配置主密钥时不接受密文
+	encrypted, _ := keyring.Encrypt("pa55")
+	if err := DecryptSecrets(nil, &encrypted); err == nil {
+		t.Fatal("decrypt without master key succeeded")
+	}
+	if err := DecryptSecrets(nil, &plain); err != nil {
+		t.Fatal(err)
+	}
+}
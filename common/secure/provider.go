@@ -0,0 +1,227 @@
+package secure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 密钥提供者，按名称读取密钥，配置文件中只保存密钥引用
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+type KeyProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// 密钥提供者类型
+const (
+	ProviderFile  = "file"  // 目录下每个密钥一个文件，适用于挂载的Secret卷
+	ProviderEnv   = "env"   // 环境变量
+	ProviderVault = "vault" // 兼容Vault KV v2接口的密钥服务
+)
+
+type ProviderConfig struct {
+	Type   string      `yaml:"type"`   // 提供者类型
+	Dir    string      `yaml:"dir"`    // 密钥文件目录
+	Prefix string      `yaml:"prefix"` // 环境变量前缀
+	Vault  VaultConfig `yaml:"vault"`  // Vault配置
+}
+
+type VaultConfig struct {
+	Address string        `yaml:"address"` // 服务地址，如 https://vault:8200
+	Token   string        `yaml:"token"`   // 访问令牌，为空时读取环境变量 VAULT_TOKEN
+	Mount   string        `yaml:"mount"`   // KV引擎挂载路径
+	Path    string        `yaml:"path"`    // 密钥路径，该路径下每个字段是一个密钥
+	Timeout time.Duration `yaml:"timeout"` // 请求超时
+}
+
+func (c *ProviderConfig) Check() error {
+
+	if c.Type == "" {
+		c.Type = ProviderEnv
+	}
+
+	switch c.Type {
+	case ProviderFile:
+		if c.Dir == "" {
+			return fmt.Errorf("secret provider dir is required")
+		}
+	case ProviderEnv:
+		if c.Prefix == "" {
+			c.Prefix = "ZDAN_SECRET_"
+		}
+	case ProviderVault:
+		if c.Vault.Address == "" || c.Vault.Path == "" {
+			return fmt.Errorf("secret provider vault address and path are required")
+		}
+		if c.Vault.Token == "" {
+			c.Vault.Token = os.Getenv("VAULT_TOKEN")
+		}
+		if c.Vault.Mount == "" {
+			c.Vault.Mount = "secret"
+		}
+		if c.Vault.Timeout <= 0 {
+			c.Vault.Timeout = 5 * time.Second
+		}
+	default:
+		return fmt.Errorf("invalid secret provider type: %s", c.Type)
+	}
+
+	return nil
+}
+
+func NewKeyProvider(cfg ProviderConfig) KeyProvider {
+	switch cfg.Type {
+	case ProviderFile:
+		return &FileProvider{Dir: cfg.Dir}
+	case ProviderVault:
+		return NewVaultProvider(cfg.Vault)
+	default:
+		return &EnvProvider{Prefix: cfg.Prefix}
+	}
+}
+
+// 密钥引用前缀，如 secret:cloud-dmapp-key
+const SecretRefPrefix = "secret:"
+
+// 解析配置值，密钥引用从提供者读取，其他值原样返回
+func ResolveSecret(ctx context.Context, provider KeyProvider, value string) (string, error) {
+	name, ok := strings.CutPrefix(value, SecretRefPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	secret, err := provider.GetSecret(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %s: %w", name, err)
+	}
+
+	return secret, nil
+}
+
+// 依次解析多个配置值
+func ResolveSecrets(ctx context.Context, provider KeyProvider, values ...*string) error {
+	for _, value := range values {
+		resolved, err := ResolveSecret(ctx, provider, *value)
+		if err != nil {
+			return err
+		}
+		*value = resolved
+	}
+	return nil
+}
+
+type FileProvider struct {
+	Dir string
+}
+
+func (p *FileProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(p.Dir, filepath.Base(name)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrSecretNotFound
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+type EnvProvider struct {
+	Prefix string
+}
+
+// 环境变量名为前缀加大写名称，非字母数字替换为下划线
+func (p *EnvProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	key := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+
+	value, ok := os.LookupEnv(p.Prefix + key)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return value, nil
+}
+
+// 首次读取时拉取整个路径下的密钥并缓存
+type VaultProvider struct {
+	cfg     VaultConfig
+	client  *http.Client
+	mutex   sync.Mutex
+	secrets map[string]string
+}
+
+func NewVaultProvider(cfg VaultConfig) *VaultProvider {
+	return &VaultProvider{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (p *VaultProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.secrets == nil {
+		secrets, err := p.fetch(ctx)
+		if err != nil {
+			return "", err
+		}
+		p.secrets = secrets
+	}
+
+	value, ok := p.secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	return value, nil
+}
+
+// 读取KV v2密钥，响应格式为 {"data": {"data": {...}}}
+func (p *VaultProvider) fetch(ctx context.Context) (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(p.cfg.Address, "/"), p.cfg.Mount, strings.TrimLeft(p.cfg.Path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.cfg.Token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if body.Data.Data == nil {
+		return map[string]string{}, nil
+	}
+
+	return body.Data.Data, nil
+}
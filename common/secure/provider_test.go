@@ -0,0 +1,155 @@
+package secure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db_password"), []byte("pa55\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewKeyProvider(ProviderConfig{Type: ProviderFile, Dir: dir})
+
+	value, err := provider.GetSecret(context.Background(), "db_password")
+	if err != nil || value != "pa55" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	if _, err := provider.GetSecret(context.Background(), "missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("got %v, want ErrSecretNotFound", err)
+	}
+
+	// 名称只取文件名，不能读取目录外的文件
+	if _, err := provider.GetSecret(context.Background(), "../"+filepath.Base(dir)+"/db_password"); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := provider.GetSecret(context.Background(), "../../etc/passwd"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("got %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestEnvProvider(t *testing.T) {
+	cfg := ProviderConfig{}
+	if err := cfg.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Type != ProviderEnv || cfg.Prefix != "ZDAN_SECRET_" {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+
+	t.Setenv("ZDAN_SECRET_CLOUD_DMAPP_KEY", "cloud")
+	t.Setenv("ZDAN_SECRET_EMPTY", "")
+
+	provider := NewKeyProvider(cfg)
+
+	value, err := provider.GetSecret(context.Background(), "cloud-dmapp.key")
+	if err != nil || value != "cloud" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	// 已设置的空值与未设置区分
+	if value, err := provider.GetSecret(context.Background(), "empty"); err != nil || value != "" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	if _, err := provider.GetSecret(context.Background(), "missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("got %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestVaultProvider(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v1/kv/data/zdan/openserver" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"data":{"db_password":"pa55","master-key-v1":"a2V5"},"metadata":{"version":3}}}`))
+	}))
+	defer server.Close()
+
+	cfg := ProviderConfig{Type: ProviderVault, Vault: VaultConfig{Address: server.URL + "/", Token: "root", Mount: "kv", Path: "/zdan/openserver"}}
+	if err := cfg.Check(); err != nil {
+		t.Fatal(err)
+	}
+	provider := NewKeyProvider(cfg)
+
+	value, err := provider.GetSecret(context.Background(), "db_password")
+	if err != nil || value != "pa55" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+
+	if _, err := provider.GetSecret(context.Background(), "missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("got %v, want ErrSecretNotFound", err)
+	}
+
+	// 首次读取后使用缓存
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("vault requested %d times, want 1", n)
+	}
+
+	denied := NewVaultProvider(VaultConfig{Address: server.URL, Token: "bad", Mount: "kv", Path: "zdan/openserver", Timeout: time.Second})
+	if _, err := denied.GetSecret(context.Background(), "db_password"); err == nil {
+		t.Fatal("vault with bad token succeeded")
+	}
+}
+
+func TestProviderConfigCheck(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "from-env")
+
+	cfg := ProviderConfig{Type: ProviderVault, Vault: VaultConfig{Address: "http://vault:8200", Path: "zdan"}}
+	if err := cfg.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Vault.Token != "from-env" || cfg.Vault.Mount != "secret" || cfg.Vault.Timeout != 5*time.Second {
+		t.Fatalf("unexpected vault defaults %+v", cfg.Vault)
+	}
+
+	for _, invalid := range []ProviderConfig{
+		{Type: ProviderFile},
+		{Type: ProviderVault},
+		{Type: "unknown"},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Fatalf("config %+v accepted", invalid)
+		}
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	provider := mapProvider{"db_password": "pa55", "api-server-key": "key"}
+
+	password := "secret:db_password"
+	apiServerKey := "secret:api-server-key"
+	plain := "plain-value"
+	if err := ResolveSecrets(context.Background(), provider, &password, &apiServerKey, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if password != "pa55" || apiServerKey != "key" || plain != "plain-value" {
+		t.Fatalf("resolved %q %q %q", password, apiServerKey, plain)
+	}
+
+	missing := "secret:missing"
+	if err := ResolveSecrets(context.Background(), provider, &missing); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("got %v, want ErrSecretNotFound", err)
+	}
+	if missing != "secret:missing" {
+		t.Fatalf("failed reference was overwritten: %q", missing)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"errors"
 )
 
+// 旧版API密钥存储方式，密钥改为保存查找哈希后仅用于迁移已有数据
+
 // 硬编码加密密钥（长度必须是 16, 24 或 32 字节）
 var aesKey = []byte("Ztwv2hV14r2smkZ3WXYQFFj6sY6gjguu") // 32字节 = AES-256
 
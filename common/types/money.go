@@ -0,0 +1,149 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	inf "gopkg.in/inf.v0"
+)
+
+// 金额保存的小数位数
+const AmountDecimals = 8
+
+// 金额，使用十进制精确计算，零值表示0
+type Amount struct {
+	dec inf.Dec
+}
+
+// 由整数值和小数位数创建金额，如 NewAmount(125, 2) 表示 1.25
+func NewAmount(unscaled int64, decimals int32) Amount {
+	var a Amount
+	a.dec.SetUnscaled(unscaled).SetScale(inf.Scale(decimals))
+	return a
+}
+
+func ParseAmount(str string) (Amount, error) {
+	var a Amount
+	if _, ok := a.dec.SetString(strings.TrimSpace(str)); !ok {
+		return Amount{}, fmt.Errorf("invalid amount: %q", str)
+	}
+	return a, nil
+}
+
+func MustParseAmount(str string) Amount {
+	a, err := ParseAmount(str)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func (a Amount) Add(b Amount) Amount {
+	var r Amount
+	r.dec.Add(&a.dec, &b.dec)
+	return r
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	var r Amount
+	r.dec.Sub(&a.dec, &b.dec)
+	return r
+}
+
+func (a Amount) Mul(b Amount) Amount {
+	var r Amount
+	r.dec.Mul(&a.dec, &b.dec)
+	return r
+}
+
+func (a Amount) MulInt64(n int64) Amount {
+	return a.Mul(NewAmount(n, 0))
+}
+
+// 除以整数，结果按指定小数位数四舍五入
+func (a Amount) DivInt64(n int64, decimals int32) Amount {
+	var r Amount
+	r.dec.QuoRound(&a.dec, inf.NewDec(n, 0), inf.Scale(decimals), inf.RoundHalfUp)
+	return r
+}
+
+// 按指定小数位数四舍五入
+func (a Amount) Round(decimals int32) Amount {
+	var r Amount
+	r.dec.Round(&a.dec, inf.Scale(decimals), inf.RoundHalfUp)
+	return r
+}
+
+func (a Amount) Neg() Amount {
+	var r Amount
+	r.dec.Neg(&a.dec)
+	return r
+}
+
+func (a Amount) Cmp(b Amount) int {
+	return a.dec.Cmp(&b.dec)
+}
+
+func (a Amount) Sign() int {
+	return a.dec.Sign()
+}
+
+func (a Amount) IsZero() bool {
+	return a.dec.Sign() == 0
+}
+
+func (a Amount) String() string {
+	return a.dec.String()
+}
+
+// 序列化为字符串，避免浮点精度丢失
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// 支持字符串和数字
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if str == "null" {
+		return nil
+	}
+
+	str = strings.Trim(str, `"`)
+	parsed, err := ParseAmount(str)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// 写入数据库 NUMERIC 字段
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// 读取数据库 NUMERIC 字段
+func (a *Amount) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*a = Amount{}
+		return nil
+	case string:
+		parsed, err := ParseAmount(value)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	case []byte:
+		return a.Scan(string(value))
+	case int64:
+		*a = NewAmount(value, 0)
+		return nil
+	case float64:
+		return a.Scan(fmt.Sprintf("%v", value))
+	}
+	return fmt.Errorf("cannot scan %T into Amount", src)
+}
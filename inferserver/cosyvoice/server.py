@@ -57,6 +57,10 @@ def generate_data(model_output, sample_rate=16000):
     for tts_speech in model_output:
         yield (tts_speech['tts_speech'].numpy() * (2 ** 15)).astype(np.int16).tobytes()
 
+@app.get("/health")
+def health():
+    return {"status": "ok"}
+
 @app.post("/v1/audio/speech")
 def inference_v1(request: OpenSpeechRequest):    
     result = cosyvoice.inference_instruct2(request.input, request.instructions, prompt_speech_16k, speed=request.speed, stream=request.stream)
@@ -23,5 +23,6 @@ app.state.model = model
 app.include_router(chat_completions_router)
 
 @app.get("/")
+@app.get("/health")
 def health():
     return {"status": "ok"}
\ No newline at end of file
@@ -42,6 +42,10 @@ class OpenAIImageGenerationsResponse(BaseModel):
     data: List[Dict]
     usage: Optional[Dict] = None
 
+@app.get("/health")
+def health():
+    return {"status": "ok"}
+
 @app.post("/v1/images/generations")
 def inference_v1(request: OpenAIImageGenerationsRequest):    
     image = generate_image(
@@ -16,6 +16,8 @@ type Config struct {
 	Log      logger.Config  `yaml:"log"`
 	Zdan     ZdanConfig     `yaml:"zdan"`
 	Database DatabaseConfig `yaml:"database"`
+	Secure   SecureConfig   `yaml:"secure"`
+	Ledger   LedgerConfig   `yaml:"ledger"`
 }
 
 func (c *Config) Check() error {
@@ -24,6 +26,15 @@ func (c *Config) Check() error {
 		c.Log = logger.DefaultConfig()
 	}
 
+	if err := c.Secure.Check(); err != nil {
+		return err
+	}
+
+	// 密钥引用从密钥提供者读取
+	if err := c.Secure.ResolveSecrets(&c.Zdan.CloudDmappKey, &c.Zdan.UserDmappKey, &c.Zdan.ApiServerKey, &c.Database.Password); err != nil {
+		return err
+	}
+
 	if err := c.Zdan.Check(); err != nil {
 		return err
 	}
@@ -32,6 +43,10 @@ func (c *Config) Check() error {
 		return err
 	}
 
+	if err := c.Ledger.Check(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -51,6 +66,14 @@ func GetDatabase() *DatabaseConfig {
 	return &config.Database
 }
 
+func GetSecure() *SecureConfig {
+	return &config.Secure
+}
+
+func GetLedger() *LedgerConfig {
+	return &config.Ledger
+}
+
 func Load(filename string) error {
 
 	if !filepath.IsAbs(filename) {
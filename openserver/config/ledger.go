@@ -0,0 +1,9 @@
+package config
+
+type LedgerConfig struct {
+	Prepaid bool `yaml:"prepaid"` // 预付费，未开户的用户视为余额为0，网关拒绝调用直到充值
+}
+
+func (c *LedgerConfig) Check() error {
+	return nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"common/secure"
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+type SecureConfig struct {
+	Provider      secure.ProviderConfig `yaml:"provider"`      // 密钥提供者，配置中 secret:<名称> 形式的值从提供者读取
+	MasterKeys    secure.KeyringConfig  `yaml:"masterKeys"`    // 敏感数据信封加密的主密钥版本
+	KeyHashSecret string                `yaml:"keyHashSecret"` // 计算API密钥查找哈希的HMAC密钥，修改后已有密钥全部失效
+	provider      secure.KeyProvider
+	keyring       *secure.Keyring
+}
+
+func (c *SecureConfig) Check() error {
+
+	if err := c.Provider.Check(); err != nil {
+		return err
+	}
+	c.provider = secure.NewKeyProvider(c.Provider)
+
+	if err := c.MasterKeys.Check(); err != nil {
+		return err
+	}
+
+	if c.MasterKeys.Current != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		keyring, err := secure.NewKeyring(ctx, c.provider, c.MasterKeys)
+		if err != nil {
+			return err
+		}
+		c.keyring = keyring
+	}
+
+	if secret := os.Getenv("ZDAN_KEY_HASH_SECRET"); len(secret) > 0 {
+		c.KeyHashSecret = secret
+	}
+
+	if err := c.ResolveSecrets(&c.KeyHashSecret); err != nil {
+		return err
+	}
+
+	if len(c.KeyHashSecret) == 0 {
+		return fmt.Errorf("key hash secret is not configured, set secure.keyHashSecret or ZDAN_KEY_HASH_SECRET")
+	}
+
+	if len(c.KeyHashSecret) < 16 {
+		return fmt.Errorf("invalid key hash secret, at least 16 characters")
+	}
+
+	return nil
+}
+
+func (c *SecureConfig) KeyProvider() secure.KeyProvider {
+	return c.provider
+}
+
+// 信封加密主密钥，未配置时返回nil
+func (c *SecureConfig) Keyring() *secure.Keyring {
+	return c.keyring
+}
+
+// 解析配置中的密钥引用，enc: 形式的密文使用主密钥解密
+func (c *SecureConfig) ResolveSecrets(values ...*string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := secure.ResolveSecrets(ctx, c.provider, values...); err != nil {
+		return err
+	}
+
+	return secure.DecryptSecrets(c.keyring, values...)
+}
@@ -1,23 +1,33 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"common/logger"
 	"openserver/config"
+	"openserver/metrics"
 	"openserver/middleware/auth"
 	"openserver/rest/api_key"
 	"openserver/rest/api_service"
 	"openserver/rest/gateway"
+	"openserver/rest/ledger"
+	"openserver/rest/model_alias"
 	"openserver/rest/platform_model"
 	"openserver/rest/platform_service"
+	"openserver/rest/system_config"
+	"openserver/rest/usage"
 	"openserver/rest/user"
 	"openserver/rest/workspace"
 
 	"openserver/middleware"
 	"openserver/repository"
 	"openserver/rest"
+	"openserver/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +38,11 @@ func main() {
 	configFileName := flag.String("config", "config/config.yaml", "config file name")
 	host := flag.String("host", "", "listen ip")
 	port := flag.Int("port", 8080, "listen port")
+	metricsHost := flag.String("metrics-host", "", "metrics listen ip")
+	metricsPort := flag.Int("metrics-port", 9091, "metrics listen port, only expose on the internal network, 0 disables")
+	migrateKeys := flag.Bool("migrate-keys", false, "migrate legacy encrypted api keys to lookup hashes and exit")
+	rotateKeys := flag.Bool("rotate-keys", false, "re-encrypt secrets under the current master key version and exit")
+	encryptSecret := flag.Bool("encrypt-secret", false, "encrypt a secret read from stdin under the current master key version, print it and exit")
 
 	flag.Parse()
 
@@ -43,6 +58,17 @@ func main() {
 	logger.Info("Application started", logger.Any("config", config.GetConfig()))
 	gin.DefaultWriter = logger.GetWriter()
 
+	// 加密标准输入中的密钥后退出，输出可直接用作配置值
+	if *encryptSecret {
+		encrypted, err := encryptStdin()
+		if err != nil {
+			fmt.Println("Failed to encrypt secret:", err)
+			return
+		}
+		fmt.Println(encrypted)
+		return
+	}
+
 	// 初始化数据库
 	if err := repository.Init(); err != nil {
 		logger.Error("failed to connect to database:", logger.Err(err))
@@ -51,10 +77,50 @@ func main() {
 
 	defer repository.Close()
 
+	// 配置中的系统配置引用从数据库读取，敏感配置解密后使用
+	zdan := config.GetZdan()
+	if err := service.SystemConfig().ResolveConfig(context.Background(), &zdan.CloudDmappKey, &zdan.UserDmappKey, &zdan.ApiServerKey); err != nil {
+		logger.Error("failed to resolve system configs:", logger.Err(err))
+		return
+	}
+
+	// 使用当前主密钥重新加密后退出
+	if *rotateKeys {
+		count, err := service.SystemConfig().RotateSecrets(context.Background())
+		if err != nil {
+			logger.Error("failed to rotate secrets:", logger.Err(err))
+			return
+		}
+		logger.Info("Secrets rotated", logger.Int("count", count), logger.String("version", config.GetSecure().MasterKeys.Current))
+		return
+	}
+
+	// 迁移旧版API密钥后退出
+	if *migrateKeys {
+		count, err := service.ApiKey().MigrateLegacy(context.Background())
+		if err != nil {
+			logger.Error("failed to migrate api keys:", logger.Err(err))
+			return
+		}
+		logger.Info("Api keys migrated", logger.Int("count", count))
+		return
+	}
+
+	// 数据库连接池指标
+	if err := metrics.RegisterPool(repository.GetPool()); err != nil {
+		logger.Error("failed to register pool metrics:", logger.Err(err))
+		return
+	}
+
+	// 指标单独监听，不在对外端口暴露
+	if *metricsPort > 0 {
+		go RunMetricsServer(*metricsHost, *metricsPort)
+	}
+
 	// HTTP服务
 
 	r := gin.New()
-	r.Use(middleware.GinLogger(), middleware.GinRecovery())
+	r.Use(middleware.GinLogger(), middleware.GinRecovery(), metrics.Middleware())
 
 	// 分组路由
 	SetRouter(r)
@@ -66,6 +132,32 @@ func main() {
 	r.Run(serverAddress)
 }
 
+func encryptStdin() (string, error) {
+	keyring := config.GetSecure().Keyring()
+	if keyring == nil {
+		return "", fmt.Errorf("master key not configured")
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+
+	return keyring.Encrypt(strings.TrimRight(string(data), "\r\n"))
+}
+
+func RunMetricsServer(host string, port int) {
+
+	r := gin.New()
+	r.Use(middleware.GinRecovery())
+	r.GET("/metrics", metrics.Handler())
+
+	serverAddress := fmt.Sprintf("%s:%d", host, port)
+	if err := r.Run(serverAddress); err != nil {
+		logger.Error("RunMetricsServer", logger.Err(err))
+	}
+}
+
 func SetRouter(r *gin.Engine) {
 	SetUserRouter(r)
 	SetGatewayRouter(r)
@@ -90,8 +182,26 @@ func SetUserRouter(r *gin.Engine) {
 	u = r.Group("/v1/key", auth.ZUserAuthHander())
 	{
 		u.POST("/create", auth.ZUserAuthHander(), api_key.NewCreateHandler())
+		u.POST("/update", auth.ZUserAuthHander(), api_key.NewUpdateHandler())
+		u.POST("/rotate", auth.ZUserAuthHander(), api_key.NewRotateHandler())
+		u.POST("/disable", auth.ZUserAuthHander(), api_key.NewDisableHandler())
+		u.POST("/enable", auth.ZUserAuthHander(), api_key.NewEnableHandler())
 		u.POST("/delete", auth.ZUserAuthHander(), api_key.NewDeleteHandler())
 		u.GET("/list", auth.ZUserAuthHander(), api_key.NewListHandler())
+		u.GET("/expiring", auth.ZUserAuthHander(), api_key.NewExpiringHandler())
+	}
+
+	u = r.Group("/v1/usage", auth.ZUserAuthHander())
+	{
+		u.GET("/summary", usage.NewSummaryHandler())
+		u.GET("/series", usage.NewSeriesHandler())
+	}
+
+	u = r.Group("/v1/balance", auth.ZUserAuthHander())
+	{
+		u.GET("/info", ledger.NewBalanceHandler())
+		u.GET("/transactions", ledger.NewTransactionsHandler())
+		u.GET("/statement", ledger.NewStatementHandler())
 	}
 }
 
@@ -100,6 +210,12 @@ func SetGatewayRouter(r *gin.Engine) {
 	{
 		u.GET("/key/info", gateway.NewKeyInfoHandler())
 		u.GET("/model/services", gateway.NewModelServicesHandler())
+		u.GET("/model/infos", gateway.NewModelInfosHandler())
+		u.GET("/model/aliases", gateway.NewModelAliasesHandler())
+		u.GET("/model/splits", gateway.NewModelSplitsHandler())
+		u.POST("/usage/report", gateway.NewUsageReportHandler())
+		u.GET("/usage/summary", gateway.NewUsageSummaryHandler())
+		u.GET("/watch", gateway.NewWatchHandler())
 	}
 }
 
@@ -115,11 +231,42 @@ func SetCloudRouter(r *gin.Engine) {
 		u.POST("/create", platform_model.NewCreateHandler())
 		u.POST("/delete", platform_model.NewDeleteHandler())
 		u.GET("/list", platform_model.NewListHandler())
+
+		u.POST("/price/create", platform_model.NewPriceCreateHandler())
+		u.POST("/price/delete", platform_model.NewPriceDeleteHandler())
+		u.GET("/price/list", platform_model.NewPriceListHandler())
+	}
+
+	u = r.Group("/v1/alias", auth.ZCloudAuthHander())
+	{
+		u.POST("/set", model_alias.NewSetHandler())
+		u.POST("/delete", model_alias.NewDeleteHandler())
+		u.GET("/list", model_alias.NewListHandler())
 	}
 
 	u = r.Group("/v1/ps", auth.ZCloudAuthHander())
 	{
 		u.POST("/deploy", platform_service.NewDeployHandler())
 		u.POST("/release", platform_service.NewReleaseHandler())
+
+		u.POST("/split/set", platform_service.NewSplitSetHandler())
+		u.POST("/split/delete", platform_service.NewSplitDeleteHandler())
+		u.GET("/split/list", platform_service.NewSplitListHandler())
+	}
+
+	u = r.Group("/v1/ledger", auth.ZCloudAuthHander())
+	{
+		u.POST("/topup", ledger.NewTopUpHandler())
+	}
+
+	u = r.Group("/v1/tier", auth.ZCloudAuthHander())
+	{
+		u.POST("/update", workspace.NewTierUpdateHandler())
+	}
+
+	u = r.Group("/v1/sysconfig", auth.ZCloudAuthHander())
+	{
+		u.POST("/set", system_config.NewSetHandler())
+		u.GET("/list", system_config.NewListHandler())
 	}
 }
@@ -1,18 +1,93 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+)
 
 type ApiKey struct {
-	ID          string     `json:"id"`
-	UserID      string     `json:"userID"`
-	WorkspaceID string     `json:"workspaceID"`
-	Description string     `json:"description,omitempty"`
-	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
-	UpdatedAt   time.Time  `json:"updateAt"`
-	CreatedAt   time.Time  `json:"createAt"`
+	ID                string     `json:"id"`     // 密钥的查找哈希，密钥本身只在创建时返回
+	Prefix            string     `json:"prefix"` // 遮盖后的密钥，用于辨认
+	UserID            string     `json:"userID"`
+	WorkspaceID       string     `json:"workspaceID"`
+	Description       string     `json:"description,omitempty"`
+	ExpiresAt         *time.Time `json:"expiresAt,omitempty"`
+	Disabled          bool       `json:"disabled"`             // 停用后网关拒绝调用，可重新启用
+	LastUsedAt        *time.Time `json:"lastUsedAt,omitempty"` // 最近调用时间，由网关上报更新
+	LastUsedIP        string     `json:"lastUsedIP,omitempty"` // 最近调用的来源地址
+	ApiKeyRestriction `json:",inline"`
+	UpdatedAt         time.Time `json:"updateAt"`
+	CreatedAt         time.Time `json:"createAt"`
+}
+
+// 旧版加密保存的密钥迁移为查找哈希
+type ApiKeyMigration struct {
+	OldID  string // 旧版加密后的密钥
+	NewID  string // 查找哈希
+	Prefix string // 遮盖后的密钥
+}
+
+// 网关上报的密钥最近调用
+type ApiKeyUsage struct {
+	ApiKey     string // 密钥，保存前转为查找哈希
+	LastUsedAt time.Time
+	LastUsedIP string
 }
 
 type ApiKeyEx struct {
 	ApiKey        `json:",inline"`
 	WorkspaceName string `json:"workspaceName,omitempty"`
 }
+
+// 密钥可调用的接口范围
+const (
+	ScopeChat       = "chat"       // 对话补全
+	ScopeEmbeddings = "embeddings" // 向量
+	ScopeRerank     = "rerank"     // 重排序
+	ScopeClassify   = "classify"   // 分类
+	ScopeTokenize   = "tokenize"   // 分词
+	ScopeAudio      = "audio"      // 语音识别与合成
+	ScopeImages     = "images"     // 图像生成
+	ScopeModels     = "models"     // 模型列表
+	ScopeUsage      = "usage"      // 只读查询使用量
+)
+
+var ApiKeyScopes = []string{ScopeChat, ScopeEmbeddings, ScopeRerank, ScopeClassify, ScopeTokenize, ScopeAudio, ScopeImages, ScopeModels, ScopeUsage}
+
+// 密钥限制，字段为空表示不限制，继承工作空间的全部权限
+type ApiKeyRestriction struct {
+	Scopes       []string `json:"scopes,omitempty"`       // 允许调用的接口
+	Models       []string `json:"models,omitempty"`       // 允许调用的模型，须为工作空间已授权的模型
+	AllowedIPs   []string `json:"allowedIPs,omitempty"`   // 允许的来源地址，CIDR或单个IP
+	RequestLimit int64    `json:"requestLimit,omitempty"` // 请求数限流（次/分钟）
+	TokenLimit   int64    `json:"tokenLimit,omitempty"`   // Token限流（Tokens/分钟）
+}
+
+// 检查限制是否合法，单个IP转为CIDR
+func (r *ApiKeyRestriction) Check() error {
+	for _, scope := range r.Scopes {
+		if !slices.Contains(ApiKeyScopes, scope) {
+			return fmt.Errorf("invalid scope: %s", scope)
+		}
+	}
+
+	for i, allowed := range r.AllowedIPs {
+		if addr, err := netip.ParseAddr(allowed); err == nil {
+			r.AllowedIPs[i] = netip.PrefixFrom(addr, addr.BitLen()).String()
+			continue
+		}
+		prefix, err := netip.ParsePrefix(allowed)
+		if err != nil {
+			return fmt.Errorf("invalid allowed ip: %s", allowed)
+		}
+		r.AllowedIPs[i] = prefix.Masked().String()
+	}
+
+	if r.RequestLimit < 0 || r.TokenLimit < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+
+	return nil
+}
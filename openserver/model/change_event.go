@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// 变更类型，API网关据此清理对应的缓存
+const (
+	ChangeKey       = "key"       // 密钥删除、停用、轮换或修改限制
+	ChangeWorkspace = "workspace" // 工作空间授权、限流或等级变化
+	ChangeUser      = "user"      // 用户限流或余额变化
+	ChangeService   = "service"   // 模型服务部署、释放或预置模型变化
+)
+
+type ChangeEvent struct {
+	Revision  int64     `json:"revision"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// 监听结果，Reset 表示事件已被清理，需要清空全部缓存
+type ChangeSet struct {
+	Revision int64          `json:"revision"`
+	Reset    bool           `json:"reset,omitempty"`
+	Events   []*ChangeEvent `json:"events,omitempty"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"common/types"
+	"time"
+)
+
+// 账务流水类型
+const (
+	TransactionTopUp  = "topup"  // 充值
+	TransactionUsage  = "usage"  // 调用扣费
+	TransactionAdjust = "adjust" // 人工调整
+)
+
+// 用户账户
+type Account struct {
+	UserID    string       `json:"userID"`
+	Balance   types.Amount `json:"balance"`
+	Currency  string       `json:"currency"`
+	UpdatedAt time.Time    `json:"updateAt"`
+	CreatedAt time.Time    `json:"createAt"`
+}
+
+// 账务流水，只追加不修改
+type LedgerTransaction struct {
+	ID          int64        `json:"id"`
+	UserID      string       `json:"userID"`
+	Type        string       `json:"type"`
+	Amount      types.Amount `json:"amount"`  // 正数入账，负数扣费
+	Balance     types.Amount `json:"balance"` // 变动后余额
+	Reference   string       `json:"reference,omitempty"`
+	Description string       `json:"description,omitempty"`
+	CreatedAt   time.Time    `json:"createAt"`
+}
+
+// 流水查询参数
+type LedgerSearchParam struct {
+	UserID    string     `form:"-"`
+	Type      string     `form:"type"`
+	StartTime *time.Time `form:"startTime"`
+	EndTime   *time.Time `form:"endTime"`
+	PageIndex int        `form:"pageIndex"`
+	PageSize  int        `form:"pageSize"`
+}
+
+// 月度账单
+type Statement struct {
+	UserID         string       `json:"userID"`
+	Month          string       `json:"month"` // 格式 2006-01
+	OpeningBalance types.Amount `json:"openingBalance"`
+	TopUps         types.Amount `json:"topUps"`
+	Usage          types.Amount `json:"usage"` // 扣费总额，为正数
+	Adjustments    types.Amount `json:"adjustments"`
+	ClosingBalance types.Amount `json:"closingBalance"`
+	Transactions   int64        `json:"transactions"`
+}
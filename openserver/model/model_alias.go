@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// 模型别名，调用时解析为实际模型，用于版本切换和兼容其他平台的模型名称
+type ModelAlias struct {
+	Alias           string    `json:"alias" binding:"required"`
+	ModelName       string    `json:"modelName" binding:"required"`
+	RewriteResponse bool      `json:"rewriteResponse"` // 响应中的 model 字段改写为别名
+	Description     string    `json:"description,omitempty"`
+	CreatedAt       time.Time `json:"createAt"`
+	UpdatedAt       time.Time `json:"updateAt"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"common/types"
+	"time"
+)
+
+// 计价单位
+const (
+	PriceTokenUnit     = 1000000     // 按每百万token计价
+	PriceImagePixels   = 1024 * 1024 // 图片按 1024x1024 分辨率计价，其他分辨率按像素折算
+	PriceAudioMillis   = 1000        // 音频按每秒计价
+	PriceCharacterUnit = 10000       // 语音合成按每万字符计价
+)
+
+// 默认币种
+const DefaultCurrency = "CNY"
+
+// 模型价格，同一模型按生效时间保存多个版本
+type ModelPrice struct {
+	ID               int64        `json:"id"`
+	ModelName        string       `json:"modelName"`
+	Currency         string       `json:"currency"`
+	InputPrice       types.Amount `json:"inputPrice"`       // 每百万输入token
+	CachedInputPrice types.Amount `json:"cachedInputPrice"` // 每百万缓存命中的输入token
+	OutputPrice      types.Amount `json:"outputPrice"`      // 每百万输出token
+	ImagePrice       types.Amount `json:"imagePrice"`       // 每张图片
+	AudioPrice       types.Amount `json:"audioPrice"`       // 每秒音频
+	CharacterPrice   types.Amount `json:"characterPrice"`   // 每万字符
+	EffectiveAt      time.Time    `json:"effectiveAt"`
+	CreatedAt        time.Time    `json:"createAt"`
+}
+
+// 计算调用费用，保留 AmountDecimals 位小数
+func (p *ModelPrice) Cost(usageLog *UsageLog) types.Amount {
+	// 中间结果不舍入，最后统一四舍五入
+	const decimals = 2 * types.AmountDecimals
+
+	cachedTokens := min(usageLog.CachedTokens, usageLog.InputTokens)
+	tokenCost := p.InputPrice.MulInt64(usageLog.InputTokens-cachedTokens).
+		Add(p.CachedInputPrice.MulInt64(cachedTokens)).
+		Add(p.OutputPrice.MulInt64(usageLog.OutputTokens)).
+		DivInt64(PriceTokenUnit, decimals)
+
+	var imageCost types.Amount
+	if usageLog.ImagePixels > 0 {
+		imageCost = p.ImagePrice.MulInt64(usageLog.ImagePixels).DivInt64(PriceImagePixels, decimals)
+	} else {
+		imageCost = p.ImagePrice.MulInt64(usageLog.ImageCount)
+	}
+
+	audioCost := p.AudioPrice.MulInt64(usageLog.AudioMillis).DivInt64(PriceAudioMillis, decimals)
+	characterCost := p.CharacterPrice.MulInt64(usageLog.Characters).DivInt64(PriceCharacterUnit, decimals)
+
+	return tokenCost.Add(imageCost).Add(audioCost).Add(characterCost).Round(types.AmountDecimals)
+}
+
+// 多个模型的价格，每个模型按生效时间倒序
+type ModelPrices map[string][]*ModelPrice
+
+// 查询指定时间生效的价格，可能为空
+func (m ModelPrices) Find(modelName string, at time.Time) *ModelPrice {
+	for _, price := range m[modelName] {
+		if !price.EffectiveAt.After(at) {
+			return price
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 type PlatformModel struct {
 	Name             string      `json:"name"`
 	Provider         uint64      `json:"provider"`
+	ProviderName     string      `json:"providerName,omitempty"`
 	Classes          []uint64    `json:"classes,omitempty"`
 	Abilities        []uint64    `json:"abilities,omitempty"`
 	MaxContextLength uint64      `json:"maxContextLength"`
@@ -51,7 +52,8 @@ type SuitableGpu struct {
 
 // 部署信息
 type DeployInfo struct {
-	InferInfos []*InferInfo `json:"inferInfos,omitempty" binding:"required"` // 合适的推理引擎
+	InferInfos  []*InferInfo `json:"inferInfos,omitempty" binding:"required"` // 合适的推理引擎
+	MaxInflight int64        `json:"maxInflight,omitempty"`                   // 每个服务最多同时处理的请求数，为0时由网关按算力推算
 }
 
 func (info *InferInfo) GetPlatformInferGpu() *SuitableGpu {
@@ -61,6 +63,19 @@ func (info *InferInfo) GetPlatformInferGpu() *SuitableGpu {
 	return info.SuitableGpus[0]
 }
 
+// 按名称查找推理引擎，名称为空时使用首选引擎
+func (info *DeployInfo) FindInferInfo(name string) *InferInfo {
+	if name == "" {
+		return info.GetPlatformInferInfo()
+	}
+	for _, inferInfo := range info.InferInfos {
+		if inferInfo.Name == name {
+			return inferInfo
+		}
+	}
+	return nil
+}
+
 func (info *DeployInfo) GetPlatformInferInfo() *InferInfo {
 	if len(info.InferInfos) == 0 {
 		return nil
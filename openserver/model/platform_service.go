@@ -7,6 +7,7 @@ type PlatformService struct {
 	Name         string    `json:"name"`
 	TopoID       uint64    `json:"topoID"`
 	ModelName    string    `json:"modelName"`
+	Engine       string    `json:"engine,omitempty"` // 推理引擎名称
 	ApiServiceID string    `json:"apiServiceID"`
 	Power        uint64    `json:"power"`
 	Load         uint64    `json:"load"`
@@ -15,11 +16,13 @@ type PlatformService struct {
 }
 
 type ModelServiceInfo struct {
-	ID        string                `json:"id"`
-	ModelName string                `json:"modelName"`
-	Power     uint64                `json:"power"`
-	Load      uint64                `json:"load"`
-	Targets   []*ModelServiceTarget `json:"targets"`
+	ID          string                `json:"id"`
+	ModelName   string                `json:"modelName"`
+	Engine      string                `json:"engine,omitempty"`
+	Power       uint64                `json:"power"`
+	Load        uint64                `json:"load"`
+	MaxInflight int64                 `json:"maxInflight,omitempty"` // 最多同时处理的请求数
+	Targets     []*ModelServiceTarget `json:"targets"`
 }
 
 type ModelServiceTarget struct {
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type SystemConfig struct {
+	Key        string    `json:"key"`
+	Value      string    `json:"value,omitempty"`      // 敏感配置不返回
+	Secret     bool      `json:"secret"`               // 是否为加密保存的敏感配置
+	KeyVersion string    `json:"keyVersion,omitempty"` // 敏感配置使用的主密钥版本
+	UpdatedAt  time.Time `json:"updateAt"`
+	CreatedAt  time.Time `json:"createAt"`
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// 模型的流量分配规则，网关按权重在服务之间分配请求，并可镜像部分请求到候选服务
+type TrafficSplit struct {
+	ModelName string          `json:"modelName" binding:"required"`
+	Routes    []*TrafficRoute `json:"routes,omitempty"`
+	Sticky    bool            `json:"sticky"`           // 按API密钥保持分配结果
+	Shadow    *TrafficShadow  `json:"shadow,omitempty"` // 镜像请求，响应直接丢弃
+	CreatedAt time.Time       `json:"createAt"`
+	UpdatedAt time.Time       `json:"updateAt"`
+}
+
+// 按服务ID或推理引擎匹配模型服务，二者只能指定一个
+type TrafficRoute struct {
+	ServiceID string `json:"serviceID,omitempty"`
+	Engine    string `json:"engine,omitempty"`
+	Weight    uint64 `json:"weight"`
+}
+
+type TrafficShadow struct {
+	ServiceID string  `json:"serviceID,omitempty"`
+	Engine    string  `json:"engine,omitempty"`
+	Percent   float64 `json:"percent"` // 镜像比例，0-100
+}
+
+func (s *TrafficSplit) Check() error {
+	if len(s.Routes) == 0 && s.Shadow == nil {
+		return fmt.Errorf("routes or shadow is required")
+	}
+
+	for _, route := range s.Routes {
+		if (route.ServiceID == "") == (route.Engine == "") {
+			return fmt.Errorf("route requires exactly one of serviceID and engine")
+		}
+		if route.Weight == 0 {
+			return fmt.Errorf("route weight must be positive")
+		}
+	}
+
+	if shadow := s.Shadow; shadow != nil {
+		if (shadow.ServiceID == "") == (shadow.Engine == "") {
+			return fmt.Errorf("shadow requires exactly one of serviceID and engine")
+		}
+		if shadow.Percent <= 0 || shadow.Percent > 100 {
+			return fmt.Errorf("shadow percent must be in (0, 100]")
+		}
+	}
+
+	return nil
+}
+
+// 服务是否匹配分配规则
+func (r *TrafficRoute) Match(service *PlatformService) bool {
+	return matchService(r.ServiceID, r.Engine, service)
+}
+
+func (s *TrafficShadow) Match(service *PlatformService) bool {
+	return matchService(s.ServiceID, s.Engine, service)
+}
+
+func matchService(serviceID, engine string, service *PlatformService) bool {
+	if serviceID != "" {
+		return service.ID == serviceID
+	}
+	return service.Engine == engine
+}
+
+// 下发给网关的流量分配规则，规则已解析为网关负责的服务ID
+type ModelTrafficSplit struct {
+	ModelName string               `json:"modelName"`
+	Routes    []*ModelTrafficRoute `json:"routes,omitempty"`
+	Sticky    bool                 `json:"sticky"`
+	Shadow    *ModelTrafficShadow  `json:"shadow,omitempty"`
+}
+
+type ModelTrafficRoute struct {
+	ServiceIDs []string `json:"serviceIDs"`
+	Weight     uint64   `json:"weight"`
+}
+
+type ModelTrafficShadow struct {
+	ServiceIDs []string `json:"serviceIDs"`
+	Percent    float64  `json:"percent"`
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"common/types"
+	"time"
+)
+
+// 网关上报的一批调用日志
+type UsageBatch struct {
+	Reference    string // 批次ID，同时作为扣费流水的单号
+	ApiServiceID string
+}
+
+type UsageLog struct {
+	ApiKey       string
+	UserID       string
+	WorkspaceID  string
+	ModelName    string
+	ServiceID    string
+	Target       string // 最终处理请求的副本地址
+	Attempts     int16  // 转发尝试次数
+	OccurredAt   time.Time
+	Status       int16
+	InputTokens  int64
+	OutputTokens int64
+	CachedTokens int64        // 命中缓存的输入token，已包含在输入token中
+	ResponseTime int32        // 毫秒
+	ImageCount   int64        // 生成图片数量
+	ImagePixels  int64        // 生成图片总像素
+	AudioMillis  int64        // 音频时长(毫秒)
+	Characters   int64        // 语音合成的字符数
+	Estimated    bool         // 使用量由网关统计，而非上游返回
+	Stream       *StreamStats // 流式统计，非流式调用为空
+	PriceID      *int64       // 计费使用的价格版本，未定价时为空
+	Cost         types.Amount // 调用费用
+}
+
+// 流式响应统计
+type StreamStats struct {
+	FirstTokenTime  int32   // 首个内容块耗时(毫秒)
+	StreamDuration  int32   // 流式响应总耗时(毫秒)
+	TokensPerSecond float32 // 输出速度
+	Disconnected    bool    // 客户端中途断开
+}
+
+const (
+	UsageSuccess int16 = 0 // 调用成功
+	UsageFailed  int16 = 1 // 调用失败
+)
+
+// 使用量统计维度
+const (
+	UsageGroupWorkspace = "workspace"
+	UsageGroupKey       = "key"
+	UsageGroupModel     = "model"
+)
+
+// 使用量统计时间粒度
+const (
+	UsageBucketMinute = "minute"
+	UsageBucketHour   = "hour"
+	UsageBucketDay    = "day"
+)
+
+// 使用量查询参数
+type UsageSearchParam struct {
+	UserID      string     `form:"-"`
+	GroupBy     string     `form:"groupBy"`
+	Bucket      string     `form:"bucket"`
+	WorkspaceID string     `form:"workspaceID"`
+	ModelName   string     `form:"modelName"`
+	StartTime   *time.Time `form:"startTime"`
+	EndTime     *time.Time `form:"endTime"`
+	PageIndex   int        `form:"pageIndex"`
+	PageSize    int        `form:"pageSize"`
+}
+
+// 使用量统计结果
+type UsageStat struct {
+	Bucket       *time.Time   `json:"bucket,omitempty"`
+	WorkspaceID  string       `json:"workspaceID,omitempty"`
+	ApiKey       string       `json:"apiKey,omitempty"`
+	ApiKeyPrefix string       `json:"apiKeyPrefix,omitempty"` // 遮盖后的密钥
+	ModelName    string       `json:"modelName,omitempty"`
+	Requests     int64        `json:"requests"`
+	InputTokens  int64        `json:"inputTokens"`
+	OutputTokens int64        `json:"outputTokens"`
+	Cost         types.Amount `json:"cost"`
+	Errors       int64        `json:"errors"`
+	P50Latency   float64      `json:"p50LatencyMs"`
+	P95Latency   float64      `json:"p95LatencyMs"`
+}
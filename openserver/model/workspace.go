@@ -7,6 +7,7 @@ type Workspace struct {
 	UserID    string    `json:"userID"`
 	Name      string    `json:"name"`
 	Status    string    `json:"status"`
+	Tier      int       `json:"tier"` // 服务等级，排队时等级高的优先
 	UpdatedAt time.Time `json:"updateAt"`
 	CreatedAt time.Time `json:"createAt"`
 }
@@ -14,3 +15,10 @@ type Workspace struct {
 const (
 	MaxWorkspaceCount int = 10
 )
+
+// 工作空间服务等级
+const (
+	WorkspaceTierBasic    int = 0 // 基础
+	WorkspaceTierStandard int = 1 // 标准
+	WorkspaceTierPremium  int = 2 // 高级
+)
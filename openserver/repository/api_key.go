@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"openserver/model"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -24,17 +25,28 @@ func (r *ApiKeyRepo) GetByID(ctx context.Context, id string) (*model.ApiKey, err
 	defer conn.Release()
 
 	row := conn.QueryRow(ctx, `
-		SELECT id, user_id, workspace_id, description, expires_at, created_at, updated_at 
+		SELECT id, COALESCE(prefix, ''), user_id, workspace_id, description, expires_at,
+			COALESCE(disabled, FALSE), last_used_at, COALESCE(last_used_ip, ''),
+			scopes, models, allowed_ips, request_limit, token_limit, created_at, updated_at 
 		FROM api_keys 
 		WHERE id = $1`, id)
 
 	apiKey := &model.ApiKey{}
 	if err := row.Scan(
 		&apiKey.ID,
+		&apiKey.Prefix,
 		&apiKey.UserID,
 		&apiKey.WorkspaceID,
 		&apiKey.Description,
 		&apiKey.ExpiresAt,
+		&apiKey.Disabled,
+		&apiKey.LastUsedAt,
+		&apiKey.LastUsedIP,
+		&apiKey.Scopes,
+		&apiKey.Models,
+		&apiKey.AllowedIPs,
+		&apiKey.RequestLimit,
+		&apiKey.TokenLimit,
 		&apiKey.CreatedAt,
 		&apiKey.UpdatedAt,
 	); err != nil {
@@ -62,10 +74,7 @@ func (r *ApiKeyRepo) ListByUser(ctx context.Context, userID string, pageIndex, p
 		return nil, 0, err
 	}
 
-	querySQL := `
-        SELECT a.id, a.user_id, a.workspace_id, w.name AS workspace_name, a.description, a.expires_at, a.created_at, a.updated_at
-        FROM api_keys AS a
-        JOIN workspaces AS w ON a.workspace_id = w.id
+	querySQL := apiKeyExSelect + `
         WHERE a.user_id = $1
         ORDER BY a.created_at DESC
         LIMIT $2 OFFSET $3
@@ -77,43 +86,117 @@ func (r *ApiKeyRepo) ListByUser(ctx context.Context, userID string, pageIndex, p
 	}
 	defer rows.Close()
 
+	results, err := scanApiKeyExs(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// 查询在指定时间前到期、尚未过期的密钥，按到期时间排序
+func (r *ApiKeyRepo) ListExpiring(ctx context.Context, userID string, before time.Time) ([]*model.ApiKeyEx, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	querySQL := apiKeyExSelect + `
+        WHERE a.user_id = $1 AND a.expires_at > NOW() AND a.expires_at <= $2
+        ORDER BY a.expires_at
+    `
+
+	rows, err := conn.Query(ctx, querySQL, userID, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanApiKeyExs(rows)
+}
+
+// 密钥列表查询，附带工作空间名称
+const apiKeyExSelect = `
+        SELECT a.id, COALESCE(a.prefix, ''), a.user_id, a.workspace_id, w.name AS workspace_name, a.description, a.expires_at,
+            COALESCE(a.disabled, FALSE), a.last_used_at, COALESCE(a.last_used_ip, ''),
+            a.scopes, a.models, a.allowed_ips, a.request_limit, a.token_limit, a.created_at, a.updated_at
+        FROM api_keys AS a
+        JOIN workspaces AS w ON a.workspace_id = w.id`
+
+func scanApiKeyExs(rows pgx.Rows) ([]*model.ApiKeyEx, error) {
 	var results []*model.ApiKeyEx
 	for rows.Next() {
 		apiKey := &model.ApiKeyEx{}
 		if err := rows.Scan(
 			&apiKey.ID,
+			&apiKey.Prefix,
 			&apiKey.UserID,
 			&apiKey.WorkspaceID,
 			&apiKey.WorkspaceName,
 			&apiKey.Description,
 			&apiKey.ExpiresAt,
+			&apiKey.Disabled,
+			&apiKey.LastUsedAt,
+			&apiKey.LastUsedIP,
+			&apiKey.Scopes,
+			&apiKey.Models,
+			&apiKey.AllowedIPs,
+			&apiKey.RequestLimit,
+			&apiKey.TokenLimit,
 			&apiKey.CreatedAt,
 			&apiKey.UpdatedAt,
 		); err != nil {
-			return nil, 0, err
+			return nil, err
 		}
 		results = append(results, apiKey)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, 0, err
-	}
 
-	return results, total, nil
+	return results, rows.Err()
 }
 
 func (r *ApiKeyRepo) Create(ctx context.Context, apiKey *model.ApiKey) error {
-	conn, err := GetPool().Acquire(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
+	return WithTx(ctx, func(tx pgx.Tx) error {
+		return insertApiKey(ctx, tx, apiKey)
+	})
+}
+
+// 轮换密钥，旧密钥最迟在宽限期结束时过期，返回是否找到旧密钥
+func (r *ApiKeyRepo) Rotate(ctx context.Context, oldID, userID string, graceUntil time.Time, apiKey *model.ApiKey) (bool, error) {
+	found := false
+	err := WithTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE api_keys
+			SET expires_at = LEAST(COALESCE(expires_at, $3), $3), updated_at = NOW()
+			WHERE id = $1 AND user_id = $2`, oldID, userID, graceUntil)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		found = true
+		return insertApiKey(ctx, tx, apiKey)
+	})
 
+	return found, err
+}
+
+func insertApiKey(ctx context.Context, tx pgx.Tx, apiKey *model.ApiKey) error {
 	fieldMap := map[string]any{
-		"id":           apiKey.ID,
-		"user_id":      apiKey.UserID,
-		"workspace_id": apiKey.WorkspaceID,
-		"description":  apiKey.Description,
-		"expires_at":   apiKey.ExpiresAt,
+		"id":            apiKey.ID,
+		"prefix":        apiKey.Prefix,
+		"user_id":       apiKey.UserID,
+		"workspace_id":  apiKey.WorkspaceID,
+		"description":   apiKey.Description,
+		"expires_at":    apiKey.ExpiresAt,
+		"scopes":        apiKey.Scopes,
+		"models":        apiKey.Models,
+		"allowed_ips":   apiKey.AllowedIPs,
+		"request_limit": apiKey.RequestLimit,
+		"token_limit":   apiKey.TokenLimit,
 	}
 	columns := []string{}
 	placeholders := []string{}
@@ -132,10 +215,163 @@ func (r *ApiKeyRepo) Create(ctx context.Context, apiKey *model.ApiKey) error {
 	sql := fmt.Sprintf("INSERT INTO api_keys (%s) VALUES (%s)",
 		strings.Join(columns, ", "),
 		strings.Join(placeholders, ", "))
-	_, err = conn.Exec(ctx, sql, args...)
+	_, err := tx.Exec(ctx, sql, args...)
 	return err
 }
 
+// 停用或启用密钥，返回是否找到密钥
+func (r *ApiKeyRepo) SetDisabled(ctx context.Context, id, userID string, disabled bool) (bool, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `UPDATE api_keys SET disabled = $3, updated_at = NOW() WHERE id = $1 AND user_id = $2`, id, userID, disabled)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// 批量更新最近调用，上报乱序时不回退
+func (r *ApiKeyRepo) Touch(ctx context.Context, usages []*model.ApiKeyUsage) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	batch := &pgx.Batch{}
+	for _, usage := range usages {
+		batch.Queue(`
+			UPDATE api_keys SET last_used_at = $2, last_used_ip = COALESCE(NULLIF($3, ''), last_used_ip)
+			WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)`,
+			usage.ApiKey, usage.LastUsedAt, usage.LastUsedIP)
+	}
+
+	return conn.SendBatch(ctx, batch).Close()
+}
+
+// 修改描述、过期时间和限制，返回是否找到密钥
+func (r *ApiKeyRepo) Update(ctx context.Context, apiKey *model.ApiKey) (bool, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `
+		UPDATE api_keys
+		SET description = $3, expires_at = $4, scopes = $5, models = $6, allowed_ips = $7,
+			request_limit = $8, token_limit = $9, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2`,
+		apiKey.ID,
+		apiKey.UserID,
+		apiKey.Description,
+		apiKey.ExpiresAt,
+		apiKey.Scopes,
+		apiKey.Models,
+		apiKey.AllowedIPs,
+		apiKey.RequestLimit,
+		apiKey.TokenLimit,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// 查询遮盖后的密钥，用于展示调用统计
+func (r *ApiKeyRepo) ListPrefixes(ctx context.Context, ids []string) (map[string]string, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `SELECT id, COALESCE(prefix, '') FROM api_keys WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefixes := make(map[string]string)
+	for rows.Next() {
+		var id, prefix string
+		if err := rows.Scan(&id, &prefix); err != nil {
+			return nil, err
+		}
+		prefixes[id] = prefix
+	}
+
+	return prefixes, rows.Err()
+}
+
+// 旧版数据库补充前缀列
+func (r *ApiKeyRepo) EnsurePrefixColumn(ctx context.Context) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS prefix TEXT`)
+	return err
+}
+
+// 查询尚未迁移的旧密钥，旧密钥以加密形式保存且没有前缀
+func (r *ApiKeyRepo) ListLegacyIDs(ctx context.Context) ([]string, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `SELECT id FROM api_keys WHERE prefix IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// 旧密钥改为查找哈希，同时更新调用日志中的密钥
+// 映射先写入临时表，调用日志只扫描一次
+func (r *ApiKeyRepo) Migrate(ctx context.Context, migrations []*model.ApiKeyMigration) error {
+	return WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `CREATE TEMP TABLE api_key_migrations (old_id TEXT PRIMARY KEY, new_id TEXT NOT NULL, prefix TEXT NOT NULL) ON COMMIT DROP`); err != nil {
+			return err
+		}
+
+		rows := pgx.CopyFromSlice(len(migrations), func(i int) ([]any, error) {
+			return []any{migrations[i].OldID, migrations[i].NewID, migrations[i].Prefix}, nil
+		})
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"api_key_migrations"}, []string{"old_id", "new_id", "prefix"}, rows); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE api_keys k SET id = m.new_id, prefix = m.prefix, updated_at = NOW() FROM api_key_migrations m WHERE k.id = m.old_id`); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, `UPDATE usage_logs u SET api_key = m.new_id FROM api_key_migrations m WHERE u.api_key = m.old_id`)
+		return err
+	})
+}
+
 func (r *ApiKeyRepo) Delete(ctx context.Context, id string, userID string) error {
 	pool := GetPool()
 	conn, err := pool.Acquire(ctx)
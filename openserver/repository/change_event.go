@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"openserver/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ChangeEventRepo struct{}
+
+func ChangeEvent() *ChangeEventRepo {
+	return &ChangeEventRepo{}
+}
+
+// 变更事件写入锁，版本号按提交顺序分配
+const changeEventLock = 0x6368616e6765
+
+// 保存变更事件，返回版本号
+// 序列值在提交前分配，并发写入时串行执行，避免监听方越过尚未提交的较小版本号
+func (r *ChangeEventRepo) Create(ctx context.Context, kind, target string) (int64, error) {
+	var revision int64
+	err := WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, int64(changeEventLock)); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, `INSERT INTO change_events (kind, target) VALUES ($1, $2) RETURNING revision`, kind, target).Scan(&revision)
+	})
+	return revision, err
+}
+
+// 查询保留的版本号范围，没有事件时均为0
+func (r *ChangeEventRepo) GetRange(ctx context.Context) (int64, int64, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Release()
+
+	var minRevision, maxRevision int64
+	err = conn.QueryRow(ctx, `SELECT COALESCE(MIN(revision), 0), COALESCE(MAX(revision), 0) FROM change_events`).Scan(&minRevision, &maxRevision)
+	return minRevision, maxRevision, err
+}
+
+// 查询指定版本之后的事件
+func (r *ChangeEventRepo) ListAfter(ctx context.Context, revision int64, limit int) ([]*model.ChangeEvent, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT revision, kind, COALESCE(target, ''), created_at
+		FROM change_events
+		WHERE revision > $1
+		ORDER BY revision
+		LIMIT $2`, revision, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.ChangeEvent
+	for rows.Next() {
+		event := &model.ChangeEvent{}
+		if err := rows.Scan(&event.Revision, &event.Kind, &event.Target, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// 清理过期事件，至少保留最新一条以确定当前版本
+func (r *ChangeEventRepo) DeleteBefore(ctx context.Context, before time.Time) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `
+		DELETE FROM change_events
+		WHERE created_at < $1 AND revision < (SELECT MAX(revision) FROM change_events)`, before)
+	return err
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"openserver/model"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type LedgerRepo struct{}
+
+func Ledger() *LedgerRepo {
+	return &LedgerRepo{}
+}
+
+func (r *LedgerRepo) GetAccount(ctx context.Context, userID string) (*model.Account, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	const querySQL = `SELECT user_id, balance, currency, created_at, updated_at FROM accounts WHERE user_id = $1`
+
+	var account model.Account
+	err = conn.QueryRow(ctx, querySQL, userID).Scan(
+		&account.UserID,
+		&account.Balance,
+		&account.Currency,
+		&account.CreatedAt,
+		&account.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// 记录一笔流水，同一用户、类型和单号只记录一次，返回是否新记录
+func (r *LedgerRepo) Apply(ctx context.Context, transaction *model.LedgerTransaction) (bool, error) {
+	created := false
+	err := WithTx(ctx, func(tx pgx.Tx) error {
+		if transaction.Reference != "" {
+			found, err := r.findByReference(ctx, tx, transaction)
+			if err != nil {
+				return err
+			}
+			if found != nil {
+				*transaction = *found
+				return nil
+			}
+		}
+
+		created = true
+		return r.ApplyTx(ctx, tx, transaction)
+	})
+
+	// 并发请求使用相同单号时，后提交的一方违反唯一索引，返回已记录的流水
+	if isUniqueViolation(err) && transaction.Reference != "" {
+		created = false
+		err = WithTx(ctx, func(tx pgx.Tx) error {
+			found, err := r.findByReference(ctx, tx, transaction)
+			if err != nil {
+				return err
+			}
+			if found == nil {
+				return fmt.Errorf("ledger transaction %s not found after conflict", transaction.Reference)
+			}
+			*transaction = *found
+			return nil
+		})
+	}
+
+	return created, err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// 在事务中更新余额并追加流水
+func (r *LedgerRepo) ApplyTx(ctx context.Context, tx pgx.Tx, transaction *model.LedgerTransaction) error {
+	const upsertSQL = `
+		INSERT INTO accounts (user_id, balance) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET balance = accounts.balance + EXCLUDED.balance, updated_at = NOW()
+		RETURNING balance
+	`
+
+	if err := tx.QueryRow(ctx, upsertSQL, transaction.UserID, transaction.Amount).Scan(&transaction.Balance); err != nil {
+		return err
+	}
+
+	const insertSQL = `
+		INSERT INTO ledger_transactions (user_id, type, amount, balance, reference, description)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+		RETURNING id, created_at
+	`
+
+	return tx.QueryRow(ctx, insertSQL,
+		transaction.UserID,
+		transaction.Type,
+		transaction.Amount,
+		transaction.Balance,
+		transaction.Reference,
+		transaction.Description,
+	).Scan(&transaction.ID, &transaction.CreatedAt)
+}
+
+func (r *LedgerRepo) findByReference(ctx context.Context, tx pgx.Tx, transaction *model.LedgerTransaction) (*model.LedgerTransaction, error) {
+	const querySQL = `
+		SELECT id, user_id, type, amount, balance, COALESCE(reference, ''), COALESCE(description, ''), created_at
+		FROM ledger_transactions
+		WHERE user_id = $1 AND type = $2 AND reference = $3
+	`
+
+	var found model.LedgerTransaction
+	err := tx.QueryRow(ctx, querySQL, transaction.UserID, transaction.Type, transaction.Reference).Scan(
+		&found.ID,
+		&found.UserID,
+		&found.Type,
+		&found.Amount,
+		&found.Balance,
+		&found.Reference,
+		&found.Description,
+		&found.CreatedAt)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+func (r *LedgerRepo) ListTransactions(ctx context.Context, param *model.LedgerSearchParam) ([]*model.LedgerTransaction, int, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	where := []string{"user_id = $1"}
+	args := []any{param.UserID}
+	argIdx := 2
+
+	if param.Type != "" {
+		where = append(where, fmt.Sprintf("type = $%d", argIdx))
+		args = append(args, param.Type)
+		argIdx++
+	}
+	if param.StartTime != nil {
+		where = append(where, fmt.Sprintf("created_at >= $%d", argIdx))
+		args = append(args, *param.StartTime)
+		argIdx++
+	}
+	if param.EndTime != nil {
+		where = append(where, fmt.Sprintf("created_at < $%d", argIdx))
+		args = append(args, *param.EndTime)
+		argIdx++
+	}
+
+	whereSQL := " WHERE " + strings.Join(where, " AND ")
+
+	// total count
+	var total int
+	if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM ledger_transactions"+whereSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// select rows with pagination
+	selectSQL := fmt.Sprintf(`
+		SELECT id, user_id, type, amount, balance, COALESCE(reference, ''), COALESCE(description, ''), created_at
+		FROM ledger_transactions
+		%s
+		ORDER BY id DESC
+		LIMIT $%d OFFSET $%d
+	`, whereSQL, argIdx, argIdx+1)
+
+	args = append(args, param.PageSize, (param.PageIndex-1)*param.PageSize)
+
+	rows, err := conn.Query(ctx, selectSQL, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*model.LedgerTransaction
+	for rows.Next() {
+		var transaction model.LedgerTransaction
+		if err := rows.Scan(
+			&transaction.ID,
+			&transaction.UserID,
+			&transaction.Type,
+			&transaction.Amount,
+			&transaction.Balance,
+			&transaction.Reference,
+			&transaction.Description,
+			&transaction.CreatedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+		results = append(results, &transaction)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// 统计时间范围内的账单
+func (r *LedgerRepo) Statement(ctx context.Context, statement *model.Statement, startTime, endTime time.Time) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// 期初余额为期间之前最后一笔流水的余额
+	const openingSQL = `
+		SELECT COALESCE((
+			SELECT balance FROM ledger_transactions
+			WHERE user_id = $1 AND created_at < $2
+			ORDER BY id DESC LIMIT 1
+		), 0)
+	`
+
+	if err := conn.QueryRow(ctx, openingSQL, statement.UserID, startTime).Scan(&statement.OpeningBalance); err != nil {
+		return err
+	}
+
+	const summarySQL = `
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE type = 'topup'), 0),
+			COALESCE(SUM(-amount) FILTER (WHERE type = 'usage'), 0),
+			COALESCE(SUM(amount) FILTER (WHERE type = 'adjust'), 0),
+			COUNT(*)
+		FROM ledger_transactions
+		WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+	`
+
+	if err := conn.QueryRow(ctx, summarySQL, statement.UserID, startTime, endTime).Scan(
+		&statement.TopUps,
+		&statement.Usage,
+		&statement.Adjustments,
+		&statement.Transactions,
+	); err != nil {
+		return err
+	}
+
+	statement.ClosingBalance = statement.OpeningBalance.
+		Add(statement.TopUps).
+		Sub(statement.Usage).
+		Add(statement.Adjustments)
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"openserver/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ModelAliasRepo struct{}
+
+func ModelAlias() *ModelAliasRepo {
+	return &ModelAliasRepo{}
+}
+
+func (r *ModelAliasRepo) GetByAlias(ctx context.Context, alias string) (*model.ModelAlias, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	modelAlias := &model.ModelAlias{}
+	if err := conn.QueryRow(ctx, `
+		SELECT alias, model_name, rewrite_response, COALESCE(description, ''), created_at, updated_at
+		FROM model_aliases
+		WHERE alias = $1`, alias).Scan(
+		&modelAlias.Alias,
+		&modelAlias.ModelName,
+		&modelAlias.RewriteResponse,
+		&modelAlias.Description,
+		&modelAlias.CreatedAt,
+		&modelAlias.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return modelAlias, nil
+}
+
+// 新增或修改别名
+func (r *ModelAliasRepo) Upsert(ctx context.Context, modelAlias *model.ModelAlias) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return conn.QueryRow(ctx, `
+		INSERT INTO model_aliases (alias, model_name, rewrite_response, description)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (alias) DO UPDATE
+		SET model_name = EXCLUDED.model_name, rewrite_response = EXCLUDED.rewrite_response,
+			description = EXCLUDED.description, updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		modelAlias.Alias,
+		modelAlias.ModelName,
+		modelAlias.RewriteResponse,
+		modelAlias.Description,
+	).Scan(&modelAlias.CreatedAt, &modelAlias.UpdatedAt)
+}
+
+func (r *ModelAliasRepo) Delete(ctx context.Context, alias string) (bool, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `DELETE FROM model_aliases WHERE alias = $1`, alias)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *ModelAliasRepo) List(ctx context.Context) ([]*model.ModelAlias, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT alias, model_name, rewrite_response, COALESCE(description, ''), created_at, updated_at
+		FROM model_aliases
+		ORDER BY alias`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.ModelAlias
+	for rows.Next() {
+		modelAlias := &model.ModelAlias{}
+		if err := rows.Scan(
+			&modelAlias.Alias,
+			&modelAlias.ModelName,
+			&modelAlias.RewriteResponse,
+			&modelAlias.Description,
+			&modelAlias.CreatedAt,
+			&modelAlias.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, modelAlias)
+	}
+
+	return results, rows.Err()
+}
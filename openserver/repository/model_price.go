@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"openserver/model"
+)
+
+type ModelPriceRepo struct{}
+
+func ModelPrice() *ModelPriceRepo {
+	return &ModelPriceRepo{}
+}
+
+func (r *ModelPriceRepo) Create(ctx context.Context, price *model.ModelPrice) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	const insertSQL = `
+		INSERT INTO model_prices (
+			model_name, currency, input_price, cached_input_price, output_price,
+			image_price, audio_price, character_price, effective_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	return conn.QueryRow(ctx, insertSQL,
+		price.ModelName,
+		price.Currency,
+		price.InputPrice,
+		price.CachedInputPrice,
+		price.OutputPrice,
+		price.ImagePrice,
+		price.AudioPrice,
+		price.CharacterPrice,
+		price.EffectiveAt,
+	).Scan(&price.ID, &price.CreatedAt)
+}
+
+func (r *ModelPriceRepo) Delete(ctx context.Context, id int64) (bool, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, "DELETE FROM model_prices WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// 查询模型的所有价格版本，按生效时间倒序
+func (r *ModelPriceRepo) ListByModels(ctx context.Context, modelNames []string) ([]*model.ModelPrice, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	const querySQL = `
+		SELECT
+			id, model_name, currency, input_price, cached_input_price, output_price,
+			image_price, audio_price, character_price, effective_at, created_at
+		FROM model_prices
+		WHERE model_name = ANY($1)
+		ORDER BY model_name, effective_at DESC
+	`
+
+	rows, err := conn.Query(ctx, querySQL, modelNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.ModelPrice
+	for rows.Next() {
+		var price model.ModelPrice
+		if err := rows.Scan(
+			&price.ID,
+			&price.ModelName,
+			&price.Currency,
+			&price.InputPrice,
+			&price.CachedInputPrice,
+			&price.OutputPrice,
+			&price.ImagePrice,
+			&price.AudioPrice,
+			&price.CharacterPrice,
+			&price.EffectiveAt,
+			&price.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, &price)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
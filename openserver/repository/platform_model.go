@@ -165,6 +165,57 @@ func (r *PlatformModelRepo) List(ctx context.Context, searchParams *model.Platfo
 	return results, total, nil
 }
 
+// 查询API网关已部署的模型
+func (r *PlatformModelRepo) ListByGateway(ctx context.Context, apiServiceID string) ([]*model.PlatformModel, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	const querySQL = `
+		SELECT
+			m.name, m.provider, COALESCE(p.name, ''), m.classes, m.abilities, m.max_context_length,
+			COALESCE(m.description, ''), m.status, m.created_at, m.updated_at
+		FROM platform_models AS m
+		LEFT JOIN model_providers AS p ON p.id = m.provider
+		WHERE m.name IN (SELECT model_name FROM platform_services WHERE api_service_id = $1)
+	`
+
+	rows, err := conn.Query(ctx, querySQL, apiServiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.PlatformModel
+	for rows.Next() {
+		var pm model.PlatformModel
+		if err := rows.Scan(
+			&pm.Name,
+			&pm.Provider,
+			&pm.ProviderName,
+			&pm.Classes,
+			&pm.Abilities,
+			&pm.MaxContextLength,
+			&pm.Description,
+			&pm.Status,
+			&pm.CreatedAt,
+			&pm.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		results = append(results, &pm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (r *PlatformModelRepo) Create(ctx context.Context, pm *model.PlatformModel) error {
 	conn, err := GetPool().Acquire(ctx)
 	if err != nil {
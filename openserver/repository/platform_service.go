@@ -20,7 +20,7 @@ func (r *PlatformServiceRepo) ListByGateway(ctx context.Context, apiServiceID st
 	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
-		SELECT id, name, topo_id, model_name, api_service_id, power, load, created_at, updated_at
+		SELECT id, name, topo_id, model_name, COALESCE(engine, ''), api_service_id, power, load, created_at, updated_at
 		FROM platform_services
 		WHERE api_service_id = $1`, apiServiceID)
 	if err != nil {
@@ -36,6 +36,7 @@ func (r *PlatformServiceRepo) ListByGateway(ctx context.Context, apiServiceID st
 			&service.Name,
 			&service.TopoID,
 			&service.ModelName,
+			&service.Engine,
 			&service.ApiServiceID,
 			&service.Power,
 			&service.Load,
@@ -55,6 +56,46 @@ func (r *PlatformServiceRepo) ListByGateway(ctx context.Context, apiServiceID st
 	return services, nil
 }
 
+func (r *PlatformServiceRepo) ListByModel(ctx context.Context, modelName string) ([]*model.PlatformService, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `
+		SELECT id, name, topo_id, model_name, COALESCE(engine, ''), api_service_id, power, load, created_at, updated_at
+		FROM platform_services
+		WHERE model_name = $1`, modelName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	services := []*model.PlatformService{}
+	for rows.Next() {
+		service := &model.PlatformService{}
+		err := rows.Scan(
+			&service.ID,
+			&service.Name,
+			&service.TopoID,
+			&service.ModelName,
+			&service.Engine,
+			&service.ApiServiceID,
+			&service.Power,
+			&service.Load,
+			&service.CreatedAt,
+			&service.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+
+	return services, rows.Err()
+}
+
 func (r *PlatformServiceRepo) Create(ctx context.Context, modelService *model.PlatformService) error {
 
 	pool := GetPool()
@@ -64,12 +105,13 @@ func (r *PlatformServiceRepo) Create(ctx context.Context, modelService *model.Pl
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, `INSERT INTO platform_services (id, name, topo_id, model_name, api_service_id, power, load) 
-							VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+	_, err = conn.Exec(ctx, `INSERT INTO platform_services (id, name, topo_id, model_name, engine, api_service_id, power, load) 
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
 		modelService.ID,
 		modelService.Name,
 		modelService.TopoID,
 		modelService.ModelName,
+		modelService.Engine,
 		modelService.ApiServiceID,
 		modelService.Power,
 		modelService.Load,
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"openserver/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SystemConfigRepo struct{}
+
+func SystemConfig() *SystemConfigRepo {
+	return &SystemConfigRepo{}
+}
+
+func (r *SystemConfigRepo) GetByKey(ctx context.Context, key string) (*model.SystemConfig, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	row := conn.QueryRow(ctx, `SELECT key, value, created_at, updated_at FROM system_configs WHERE key = $1`, key)
+
+	config := &model.SystemConfig{}
+	if err := row.Scan(&config.Key, &config.Value, &config.CreatedAt, &config.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return config, nil
+}
+
+func (r *SystemConfigRepo) List(ctx context.Context) ([]*model.SystemConfig, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, `SELECT key, value, created_at, updated_at FROM system_configs ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	configs := []*model.SystemConfig{}
+	for rows.Next() {
+		config := &model.SystemConfig{}
+		if err := rows.Scan(&config.Key, &config.Value, &config.CreatedAt, &config.UpdatedAt); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+func (r *SystemConfigRepo) Upsert(ctx context.Context, key, value string) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `
+		INSERT INTO system_configs (key, value) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()`, key, value)
+	return err
+}
+
+// 值未被修改时才更新，返回是否更新
+func (r *SystemConfigRepo) CompareAndSwap(ctx context.Context, key, oldValue, newValue string) (bool, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `UPDATE system_configs SET value = $3, updated_at = NOW() WHERE key = $1 AND value = $2`, key, oldValue, newValue)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"openserver/model"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TrafficSplitRepo struct{}
+
+func TrafficSplit() *TrafficSplitRepo {
+	return &TrafficSplitRepo{}
+}
+
+const trafficSplitSelect = `
+	SELECT model_name, routes, COALESCE(sticky, FALSE), shadow, created_at, updated_at
+	FROM traffic_splits`
+
+func scanTrafficSplit(row pgx.Row) (*model.TrafficSplit, error) {
+	split := &model.TrafficSplit{}
+	var routesJSON, shadowJSON []byte
+	if err := row.Scan(
+		&split.ModelName,
+		&routesJSON,
+		&split.Sticky,
+		&shadowJSON,
+		&split.CreatedAt,
+		&split.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if routesJSON != nil {
+		if err := json.Unmarshal(routesJSON, &split.Routes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal routes: %w", err)
+		}
+	}
+
+	if shadowJSON != nil {
+		if err := json.Unmarshal(shadowJSON, &split.Shadow); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal shadow: %w", err)
+		}
+	}
+
+	return split, nil
+}
+
+func (r *TrafficSplitRepo) GetByModelName(ctx context.Context, modelName string) (*model.TrafficSplit, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	split, err := scanTrafficSplit(conn.QueryRow(ctx, trafficSplitSelect+` WHERE model_name = $1`, modelName))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return split, nil
+}
+
+// 新增或替换模型的流量分配规则
+func (r *TrafficSplitRepo) Upsert(ctx context.Context, split *model.TrafficSplit) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var routesJSON, shadowJSON []byte
+	if len(split.Routes) > 0 {
+		if routesJSON, err = json.Marshal(split.Routes); err != nil {
+			return err
+		}
+	}
+	if split.Shadow != nil {
+		if shadowJSON, err = json.Marshal(split.Shadow); err != nil {
+			return err
+		}
+	}
+
+	return conn.QueryRow(ctx, `
+		INSERT INTO traffic_splits (model_name, routes, sticky, shadow)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (model_name) DO UPDATE
+		SET routes = EXCLUDED.routes, sticky = EXCLUDED.sticky, shadow = EXCLUDED.shadow, updated_at = NOW()
+		RETURNING created_at, updated_at`,
+		split.ModelName,
+		routesJSON,
+		split.Sticky,
+		shadowJSON,
+	).Scan(&split.CreatedAt, &split.UpdatedAt)
+}
+
+func (r *TrafficSplitRepo) Delete(ctx context.Context, modelName string) (bool, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `DELETE FROM traffic_splits WHERE model_name = $1`, modelName)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// 查询流量分配规则，模型名称为空时查询全部
+func (r *TrafficSplitRepo) ListByModels(ctx context.Context, modelNames []string) ([]*model.TrafficSplit, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var rows pgx.Rows
+	if len(modelNames) == 0 {
+		rows, err = conn.Query(ctx, trafficSplitSelect+` ORDER BY model_name`)
+	} else {
+		rows, err = conn.Query(ctx, trafficSplitSelect+` WHERE model_name = ANY($1) ORDER BY model_name`, modelNames)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.TrafficSplit
+	for rows.Next() {
+		split, err := scanTrafficSplit(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, split)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"openserver/model"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type UsageLogRepo struct{}
+
+func UsageLog() *UsageLogRepo {
+	return &UsageLogRepo{}
+}
+
+// 批量写入调用日志，并在同一事务中记录扣费流水
+// 同一批次只记录一次，批次已存在时直接返回，写入数量为0
+func (r *UsageLogRepo) CopyFrom(ctx context.Context, batch *model.UsageBatch, usageLogs []*model.UsageLog, debits []*model.LedgerTransaction) (int64, error) {
+	var count int64
+	err := WithTx(ctx, func(tx pgx.Tx) error {
+		// 并发的重复上报在主键上等待，先提交的一方生效
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO usage_batches (reference, api_service_id, log_count) VALUES ($1, $2, $3)
+			ON CONFLICT (reference) DO NOTHING`,
+			batch.Reference, batch.ApiServiceID, len(usageLogs))
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		count, err = r.copyFrom(ctx, tx, usageLogs)
+		if err != nil {
+			return err
+		}
+
+		for _, debit := range debits {
+			if err := Ledger().ApplyTx(ctx, tx, debit); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	return count, err
+}
+
+// 清理过期的上报批次，调用日志和扣费流水不受影响
+func (r *UsageLogRepo) DeleteBatchesBefore(ctx context.Context, before time.Time) error {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `DELETE FROM usage_batches WHERE created_at < $1`, before)
+	return err
+}
+
+func (r *UsageLogRepo) copyFrom(ctx context.Context, tx pgx.Tx, usageLogs []*model.UsageLog) (int64, error) {
+	columns := []string{
+		"api_key",
+		"user_id",
+		"workspace_id",
+		"model_name",
+		"service_id",
+		"target",
+		"attempts",
+		"occurred_at",
+		"status",
+		"input_tokens",
+		"output_tokens",
+		"cached_tokens",
+		"response_time_ms",
+		"image_count",
+		"image_pixels",
+		"audio_ms",
+		"characters",
+		"estimated",
+		"first_token_ms",
+		"stream_duration_ms",
+		"tokens_per_second",
+		"client_disconnected",
+		"price_id",
+		"cost",
+	}
+
+	rows := pgx.CopyFromSlice(len(usageLogs), func(i int) ([]any, error) {
+		usageLog := usageLogs[i]
+
+		// 非流式调用的流式统计为空
+		var firstTokenTime, streamDuration *int32
+		var tokensPerSecond *float32
+		var disconnected *bool
+		if stream := usageLog.Stream; stream != nil {
+			firstTokenTime = &stream.FirstTokenTime
+			streamDuration = &stream.StreamDuration
+			tokensPerSecond = &stream.TokensPerSecond
+			disconnected = &stream.Disconnected
+		}
+
+		return []any{
+			usageLog.ApiKey,
+			usageLog.UserID,
+			usageLog.WorkspaceID,
+			usageLog.ModelName,
+			usageLog.ServiceID,
+			usageLog.Target,
+			usageLog.Attempts,
+			usageLog.OccurredAt,
+			usageLog.Status,
+			usageLog.InputTokens,
+			usageLog.OutputTokens,
+			usageLog.CachedTokens,
+			usageLog.ResponseTime,
+			usageLog.ImageCount,
+			usageLog.ImagePixels,
+			usageLog.AudioMillis,
+			usageLog.Characters,
+			usageLog.Estimated,
+			firstTokenTime,
+			streamDuration,
+			tokensPerSecond,
+			disconnected,
+			usageLog.PriceID,
+			usageLog.Cost,
+		}, nil
+	})
+
+	return tx.CopyFrom(ctx, pgx.Identifier{"usage_logs"}, columns, rows)
+}
+
+var usageGroupColumns = map[string]string{
+	model.UsageGroupWorkspace: "workspace_id",
+	model.UsageGroupKey:       "api_key",
+	model.UsageGroupModel:     "model_name",
+}
+
+var usageBucketIntervals = map[string]string{
+	model.UsageBucketMinute: "1 minute",
+	model.UsageBucketHour:   "1 hour",
+	model.UsageBucketDay:    "1 day",
+}
+
+// 按维度和时间粒度统计使用量
+func (r *UsageLogRepo) Stats(ctx context.Context, param *model.UsageSearchParam) ([]*model.UsageStat, int, error) {
+	conn, err := GetPool().Acquire(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Release()
+
+	where := []string{"user_id = $1", "occurred_at >= $2", "occurred_at < $3"}
+	args := []any{param.UserID, *param.StartTime, *param.EndTime}
+	argIdx := 4
+
+	if param.WorkspaceID != "" {
+		where = append(where, fmt.Sprintf("workspace_id = $%d", argIdx))
+		args = append(args, param.WorkspaceID)
+		argIdx++
+	}
+	if param.ModelName != "" {
+		where = append(where, fmt.Sprintf("model_name = $%d", argIdx))
+		args = append(args, param.ModelName)
+		argIdx++
+	}
+
+	var selects, groups []string
+	if interval, ok := usageBucketIntervals[param.Bucket]; ok {
+		selects = append(selects, fmt.Sprintf("time_bucket('%s', occurred_at) AS bucket", interval))
+		groups = append(groups, "bucket")
+	}
+	groupColumn, grouped := usageGroupColumns[param.GroupBy]
+	if grouped {
+		selects = append(selects, groupColumn)
+		groups = append(groups, groupColumn)
+	}
+
+	selects = append(selects,
+		"COUNT(*)",
+		"COALESCE(SUM(input_tokens), 0)",
+		"COALESCE(SUM(output_tokens), 0)",
+		"COALESCE(SUM(cost), 0)",
+		"COUNT(*) FILTER (WHERE status <> 0)",
+		"COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY response_time_ms), 0)",
+		"COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY response_time_ms), 0)",
+	)
+
+	whereSQL := " WHERE " + strings.Join(where, " AND ")
+	groupSQL := ""
+	orderSQL := ""
+	if len(groups) > 0 {
+		groupSQL = " GROUP BY " + strings.Join(groups, ", ")
+		orderSQL = " ORDER BY " + strings.Join(groups, " DESC, ") + " DESC"
+	}
+
+	// total count
+	var total int
+	countSQL := "SELECT COUNT(*) FROM (SELECT 1 FROM usage_logs" + whereSQL + groupSQL + ") AS t"
+	if len(groups) == 0 {
+		total = 1
+	} else if err := conn.QueryRow(ctx, countSQL, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// select rows with pagination
+	selectSQL := fmt.Sprintf("SELECT %s FROM usage_logs%s%s%s LIMIT $%d OFFSET $%d",
+		strings.Join(selects, ", "), whereSQL, groupSQL, orderSQL, argIdx, argIdx+1)
+	args = append(args, param.PageSize, (param.PageIndex-1)*param.PageSize)
+
+	rows, err := conn.Query(ctx, selectSQL, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []*model.UsageStat
+	for rows.Next() {
+		stat := &model.UsageStat{}
+		var bucket time.Time
+		var groupValue string
+
+		var dest []any
+		if param.Bucket != "" {
+			dest = append(dest, &bucket)
+		}
+		if grouped {
+			dest = append(dest, &groupValue)
+		}
+		dest = append(dest,
+			&stat.Requests,
+			&stat.InputTokens,
+			&stat.OutputTokens,
+			&stat.Cost,
+			&stat.Errors,
+			&stat.P50Latency,
+			&stat.P95Latency,
+		)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, 0, err
+		}
+
+		if param.Bucket != "" {
+			stat.Bucket = &bucket
+		}
+
+		switch param.GroupBy {
+		case model.UsageGroupWorkspace:
+			stat.WorkspaceID = groupValue
+		case model.UsageGroupKey:
+			stat.ApiKey = groupValue
+		case model.UsageGroupModel:
+			stat.ModelName = groupValue
+		}
+
+		results = append(results, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
@@ -24,9 +24,9 @@ func (r *WorkspaceRepo) GetByID(ctx context.Context, id string) (*model.Workspac
 	}
 	defer conn.Release()
 
-	row := conn.QueryRow(ctx, `SELECT id, user_id, name, status, created_at, updated_at FROM workspaces WHERE id=$1`, id)
+	row := conn.QueryRow(ctx, `SELECT id, user_id, name, status, tier, created_at, updated_at FROM workspaces WHERE id=$1`, id)
 	workspace := &model.Workspace{}
-	if err := row.Scan(&workspace.ID, &workspace.UserID, &workspace.Name, &workspace.Status, &workspace.CreatedAt, &workspace.UpdatedAt); err != nil {
+	if err := row.Scan(&workspace.ID, &workspace.UserID, &workspace.Name, &workspace.Status, &workspace.Tier, &workspace.CreatedAt, &workspace.UpdatedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
@@ -62,7 +62,7 @@ func (r *WorkspaceRepo) ListByUser(ctx context.Context, userID string) ([]*model
 	defer conn.Release()
 
 	rows, err := conn.Query(ctx, `
-		SELECT id, user_id, name, status, created_at, updated_at
+		SELECT id, user_id, name, status, tier, created_at, updated_at
 		FROM workspaces
 		WHERE user_id = $1
 		ORDER BY created_at ASC
@@ -80,6 +80,7 @@ func (r *WorkspaceRepo) ListByUser(ctx context.Context, userID string) ([]*model
 			&workspace.UserID,
 			&workspace.Name,
 			&workspace.Status,
+			&workspace.Tier,
 			&workspace.CreatedAt,
 			&workspace.UpdatedAt,
 		)
@@ -134,6 +135,23 @@ func (r *WorkspaceRepo) Create(ctx context.Context, workspace *model.Workspace)
 	return err
 }
 
+// 设置服务等级，返回是否找到工作空间
+func (r *WorkspaceRepo) UpdateTier(ctx context.Context, id string, tier int) (bool, error) {
+	pool := GetPool()
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, `UPDATE workspaces SET tier = $2, updated_at = NOW() WHERE id = $1`, id, tier)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *WorkspaceRepo) Delete(ctx context.Context, id string, userID string) error {
 	pool := GetPool()
 	conn, err := pool.Acquire(ctx)
@@ -2,6 +2,7 @@ package api_key
 
 import (
 	"common"
+	"openserver/model"
 	"openserver/rest"
 	"openserver/service"
 	"time"
@@ -17,10 +18,14 @@ type CreateRequest struct {
 	WorkspaceID string     `json:"workspaceID" binding:"required"`
 	Description string     `json:"description,omitempty"`
 	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+
+	model.ApiKeyRestriction `json:",inline"`
 }
 
 type CreateResponse struct {
-	ID string `json:"id"`
+	ID     string `json:"id"`     // 密钥的查找哈希，用于删除等管理操作
+	Key    string `json:"key"`    // 密钥，只在创建时返回一次
+	Prefix string `json:"prefix"` // 遮盖后的密钥
 }
 
 func NewCreateHandler() gin.HandlerFunc {
@@ -35,11 +40,11 @@ func (h *CreateHandler) Handle() {
 	req := h.Request
 	ctx := h.GetContext()
 	userId := h.GetFromUser()
-	id, err := service.ApiKey().Create(ctx, userId, req.WorkspaceID, req.Description, req.ExpiresAt)
+	apiKey, key, err := service.ApiKey().Create(ctx, userId, req.WorkspaceID, req.Description, req.ExpiresAt, &req.ApiKeyRestriction)
 	if err != nil {
 		h.SetErrorWithDefaultCode(err, common.Failure)
 		return
 	}
 
-	h.SetResponseData(&CreateResponse{ID: id})
+	h.SetResponseData(&CreateResponse{ID: apiKey.ID, Key: key, Prefix: apiKey.Prefix})
 }
@@ -0,0 +1,35 @@
+package api_key
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DisableHandler struct {
+	rest.Handler[DisableRequest]
+}
+
+type DisableRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+func NewDisableHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &DisableHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *DisableHandler) Handle() {
+	req := h.Request
+	ctx := h.GetContext()
+	userId := h.GetFromUser()
+	if err := service.ApiKey().SetDisabled(ctx, req.ID, userId, true); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
@@ -0,0 +1,35 @@
+package api_key
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EnableHandler struct {
+	rest.Handler[EnableRequest]
+}
+
+type EnableRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+func NewEnableHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &EnableHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *EnableHandler) Handle() {
+	req := h.Request
+	ctx := h.GetContext()
+	userId := h.GetFromUser()
+	if err := service.ApiKey().SetDisabled(ctx, req.ID, userId, false); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
@@ -0,0 +1,50 @@
+package api_key
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 即将到期的密钥，用于到期提醒
+
+type ExpiringHandler struct {
+	rest.Handler[ExpiringRequest]
+}
+
+type ExpiringRequest struct {
+	Days int `form:"days" binding:"omitempty,min=1,max=90"` // 查询天数，默认7天
+}
+
+type ExpiringResponse struct {
+	Days int               `json:"days"`
+	Keys []*model.ApiKeyEx `json:"keys,omitempty"`
+}
+
+func NewExpiringHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ExpiringHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *ExpiringHandler) Handle() {
+	req := h.Request
+	ctx := h.GetContext()
+
+	if req.Days <= 0 {
+		req.Days = 7
+	}
+
+	apiKeys, err := service.ApiKey().ListExpiring(ctx, h.GetFromUser(), req.Days)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(ExpiringResponse{Days: req.Days, Keys: apiKeys})
+}
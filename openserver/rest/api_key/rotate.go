@@ -0,0 +1,54 @@
+package api_key
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RotateHandler struct {
+	rest.Handler[RotateRequest]
+}
+
+type RotateRequest struct {
+	ID          string `json:"id" binding:"required"`
+	GracePeriod *int64 `json:"gracePeriod,omitempty"` // 旧密钥继续可用的秒数，为空时为24小时，0表示立即失效
+}
+
+type RotateResponse struct {
+	CreateResponse
+	ReplacedID string `json:"replacedID"` // 被轮换的旧密钥，宽限期结束后失效
+}
+
+func NewRotateHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &RotateHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *RotateHandler) Handle() {
+	req := h.Request
+	ctx := h.GetContext()
+	userId := h.GetFromUser()
+
+	grace := service.DefaultRotateGrace
+	if req.GracePeriod != nil {
+		grace = time.Duration(*req.GracePeriod) * time.Second
+	}
+
+	apiKey, key, err := service.ApiKey().Rotate(ctx, req.ID, userId, grace)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(&RotateResponse{
+		CreateResponse: CreateResponse{ID: apiKey.ID, Key: key, Prefix: apiKey.Prefix},
+		ReplacedID:     req.ID,
+	})
+}
@@ -0,0 +1,44 @@
+package api_key
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpdateHandler struct {
+	rest.Handler[UpdateRequest]
+}
+
+type UpdateRequest struct {
+	ID          string     `json:"id" binding:"required"`
+	Description string     `json:"description,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+
+	model.ApiKeyRestriction `json:",inline"`
+}
+
+func NewUpdateHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &UpdateHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *UpdateHandler) Handle() {
+	req := h.Request
+	ctx := h.GetContext()
+	userId := h.GetFromUser()
+	apiKey, err := service.ApiKey().Update(ctx, req.ID, userId, req.Description, req.ExpiresAt, &req.ApiKeyRestriction)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(apiKey)
+}
@@ -2,6 +2,10 @@ package gateway
 
 import (
 	"common"
+	"common/secure"
+	"common/types"
+	"fmt"
+	"openserver/model"
 	"openserver/rest"
 	"openserver/service"
 	"time"
@@ -18,15 +22,32 @@ type KeyInfoHandler struct {
 type KeyInfoRequest struct {
 	ID             string `form:"id" binding:"required"`
 	WithUsageLimit bool   `form:"withUsageLimit"`
+	WithBalance    bool   `form:"withBalance"`
+	WithUserLimit  bool   `form:"withUserLimit"`
+}
+
+// 请求日志中不输出完整的API密钥
+func (r KeyInfoRequest) String() string {
+	return fmt.Sprintf("{ID:%s WithUsageLimit:%t WithBalance:%t WithUserLimit:%t}", secure.MaskApiKey(r.ID), r.WithUsageLimit, r.WithBalance, r.WithUserLimit)
 }
 
 type KeyInfoResponse struct {
-	ID          string       `json:"id"`
-	UserID      string       `json:"userID"`
-	WorkspaceID string       `json:"workspaceID"`
-	Description string       `json:"description,omitempty"`
-	ExpiresAt   *time.Time   `json:"expiresAt,omitempty"`
-	UsageLimits []UsageLimit `json:"usageLimits,omitempty"`
+	ID          string        `json:"id"`
+	UserID      string        `json:"userID"`
+	WorkspaceID string        `json:"workspaceID"`
+	Tier        int           `json:"tier"` // 工作空间服务等级
+	Description string        `json:"description,omitempty"`
+	ExpiresAt   *time.Time    `json:"expiresAt,omitempty"`
+	UsageLimits []UsageLimit  `json:"usageLimits,omitempty"`
+	UserLimit   *UserLimit    `json:"userLimit,omitempty"` // 用户汇总调用限制
+	Balance     *types.Amount `json:"balance,omitempty"`   // 预付费余额，未启用预付费且未开户时为空
+
+	model.ApiKeyRestriction `json:",inline"` // 密钥自身的限制
+}
+
+type UserLimit struct {
+	RequestLimit int64 `json:"requestLimit"`
+	TokenLimit   int64 `json:"tokenLimit"`
 }
 
 type UsageLimit struct {
@@ -47,7 +68,13 @@ func (h *KeyInfoHandler) Handle() {
 	req := h.Request
 	ctx := h.GetContext()
 
-	apiKey, err := service.ApiKey().FindByID(ctx, req.ID)
+	apiKey, err := service.ApiKey().FindByKey(ctx, req.ID)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	workspace, err := service.Workspace().FindByID(ctx, apiKey.WorkspaceID)
 	if err != nil {
 		h.SetErrorWithDefaultCode(err, common.Failure)
 		return
@@ -57,8 +84,11 @@ func (h *KeyInfoHandler) Handle() {
 		ID:          apiKey.ID,
 		UserID:      apiKey.UserID,
 		WorkspaceID: apiKey.WorkspaceID,
+		Tier:        workspace.Tier,
 		ExpiresAt:   apiKey.ExpiresAt,
 		Description: apiKey.Description,
+
+		ApiKeyRestriction: apiKey.ApiKeyRestriction,
 	}
 
 	if req.WithUsageLimit {
@@ -77,6 +107,29 @@ func (h *KeyInfoHandler) Handle() {
 		}
 	}
 
+	if req.WithUserLimit {
+		user, err := service.User().FindByID(ctx, apiKey.UserID)
+		if err != nil {
+			h.SetErrorWithDefaultCode(err, common.Failure)
+			return
+		}
+
+		response.UserLimit = &UserLimit{
+			RequestLimit: user.RequestLimit,
+			TokenLimit:   user.TokenLimit,
+		}
+	}
+
+	if req.WithBalance {
+		balance, err := service.Ledger().FindBalance(ctx, apiKey.UserID)
+		if err != nil {
+			h.SetErrorWithDefaultCode(err, common.Failure)
+			return
+		}
+
+		response.Balance = balance
+	}
+
 	h.SetResponseData(response)
 
 }
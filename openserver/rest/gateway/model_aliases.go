@@ -0,0 +1,50 @@
+package gateway
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询模型别名，网关根据本地已部署的模型过滤
+
+type ModelAliasesHandler struct {
+	rest.Handler[ModelAliasesRequest]
+}
+
+type ModelAliasesRequest struct{}
+
+type ModelAlias struct {
+	Alias           string `json:"alias"`
+	ModelName       string `json:"modelName"`
+	RewriteResponse bool   `json:"rewriteResponse"`
+}
+
+func NewModelAliasesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ModelAliasesHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *ModelAliasesHandler) Handle() {
+	aliases, err := service.ModelAlias().List(h.GetContext())
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	response := []ModelAlias{}
+	for _, alias := range aliases {
+		response = append(response, ModelAlias{
+			Alias:           alias.Alias,
+			ModelName:       alias.ModelName,
+			RewriteResponse: alias.RewriteResponse,
+		})
+	}
+
+	h.SetResponseData(response)
+}
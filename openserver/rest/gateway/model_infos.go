@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询API网关已部署模型的元数据
+
+type ModelInfosHandler struct {
+	rest.Handler[ModelInfosRequest]
+}
+
+type ModelInfosRequest struct {
+	ID string `form:"id" binding:"required"`
+}
+
+type ModelInfo struct {
+	Name             string    `json:"name"`
+	ProviderName     string    `json:"providerName,omitempty"`
+	Classes          []uint64  `json:"classes,omitempty"`
+	Abilities        []uint64  `json:"abilities,omitempty"`
+	MaxContextLength uint64    `json:"maxContextLength"`
+	Description      string    `json:"description,omitempty"`
+	CreatedAt        time.Time `json:"createAt"`
+}
+
+func NewModelInfosHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ModelInfosHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *ModelInfosHandler) Handle() {
+	req := h.Request
+	ctx := h.GetContext()
+	models, err := service.PlatformModel().ListByGateway(ctx, req.ID)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	response := []ModelInfo{}
+	for _, pm := range models {
+		response = append(response, ModelInfo{
+			Name:             pm.Name,
+			ProviderName:     pm.ProviderName,
+			Classes:          pm.Classes,
+			Abilities:        pm.Abilities,
+			MaxContextLength: pm.MaxContextLength,
+			Description:      pm.Description,
+			CreatedAt:        pm.CreatedAt,
+		})
+	}
+
+	h.SetResponseData(response)
+}
@@ -0,0 +1,38 @@
+package gateway
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询API网关负责的模型的流量分配规则
+
+type ModelSplitsHandler struct {
+	rest.Handler[ModelSplitsRequest]
+}
+
+type ModelSplitsRequest struct {
+	ID string `form:"id" binding:"required"`
+}
+
+func NewModelSplitsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ModelSplitsHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *ModelSplitsHandler) Handle() {
+	req := h.Request
+	splits, err := service.TrafficSplit().ListByGateway(h.GetContext(), req.ID)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(splits)
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"common"
+	"common/secure"
+	"fmt"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 接收API网关上报的调用日志
+
+type UsageReportHandler struct {
+	rest.Handler[UsageReportRequest]
+}
+
+type UsageReportRequest struct {
+	ApiServiceID string         `json:"apiServiceID"`
+	BatchID      string         `json:"batchID"` // 网关重试时不变，用于去重
+	KeyUsageLogs []KeyUsageLogs `json:"keyUsageLogs" binding:"required"`
+}
+
+type KeyUsageLogs struct {
+	ApiKey      string     `json:"apiKey" binding:"required"`
+	UserID      string     `json:"userID"`
+	WorkspaceID string     `json:"workspaceID"`
+	LastUsedIP  string     `json:"lastUsedIP,omitempty"` // 最近调用的来源地址
+	UsageLogs   []UsageLog `json:"usageLogs"`
+}
+
+// 请求日志中不输出完整的API密钥和调用明细
+func (r UsageReportRequest) String() string {
+	keys := make([]string, 0, len(r.KeyUsageLogs))
+	for _, keyUsageLogs := range r.KeyUsageLogs {
+		keys = append(keys, fmt.Sprintf("%s:%d", secure.MaskApiKey(keyUsageLogs.ApiKey), len(keyUsageLogs.UsageLogs)))
+	}
+	return fmt.Sprintf("{ApiServiceID:%s BatchID:%s KeyUsageLogs:%v}", r.ApiServiceID, r.BatchID, keys)
+}
+
+type UsageLog struct {
+	Timestamp    int64  `json:"timestamp"`
+	ModelName    string `json:"modelName"`
+	ServiceID    string `json:"serviceID,omitempty"`
+	Target       string `json:"target,omitempty"`
+	Attempts     int16  `json:"attempts"`
+	Status       int16  `json:"status"`
+	InputTokens  int64  `json:"inputTokens"`
+	OutputTokens int64  `json:"outputTokens"`
+	CachedTokens int64  `json:"cachedTokens"`
+	ResponseTime int32  `json:"responseTime"`
+
+	ImageCount  int64 `json:"imageCount"`
+	ImagePixels int64 `json:"imagePixels"`
+	AudioMillis int64 `json:"audioMillis"`
+	Characters  int64 `json:"characters"`
+	Estimated   bool  `json:"estimated"`
+
+	Stream          bool    `json:"stream"`
+	FirstTokenTime  int32   `json:"firstTokenTime"`
+	StreamDuration  int32   `json:"streamDuration"`
+	TokensPerSecond float32 `json:"tokensPerSecond"`
+	Disconnected    bool    `json:"disconnected"`
+}
+
+type UsageReportResponse struct {
+	Count int `json:"count"`
+}
+
+func NewUsageReportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &UsageReportHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *UsageReportHandler) Handle() {
+	req := &h.Request
+	ctx := h.GetContext()
+
+	var usageLogs []*model.UsageLog
+	var keyUsages []*model.ApiKeyUsage
+	for _, keyUsageLogs := range req.KeyUsageLogs {
+		var lastUsed int64
+		for _, usageLog := range keyUsageLogs.UsageLogs {
+			lastUsed = max(lastUsed, usageLog.Timestamp)
+
+			item := &model.UsageLog{
+				ApiKey:       keyUsageLogs.ApiKey,
+				UserID:       keyUsageLogs.UserID,
+				WorkspaceID:  keyUsageLogs.WorkspaceID,
+				ModelName:    usageLog.ModelName,
+				ServiceID:    usageLog.ServiceID,
+				Target:       usageLog.Target,
+				Attempts:     usageLog.Attempts,
+				OccurredAt:   time.UnixMilli(usageLog.Timestamp),
+				Status:       usageLog.Status,
+				InputTokens:  usageLog.InputTokens,
+				OutputTokens: usageLog.OutputTokens,
+				CachedTokens: usageLog.CachedTokens,
+				ResponseTime: usageLog.ResponseTime,
+				ImageCount:   usageLog.ImageCount,
+				ImagePixels:  usageLog.ImagePixels,
+				AudioMillis:  usageLog.AudioMillis,
+				Characters:   usageLog.Characters,
+				Estimated:    usageLog.Estimated,
+			}
+
+			// 非流式调用不记录流式统计
+			if usageLog.Stream {
+				item.Stream = &model.StreamStats{
+					FirstTokenTime:  usageLog.FirstTokenTime,
+					StreamDuration:  usageLog.StreamDuration,
+					TokensPerSecond: usageLog.TokensPerSecond,
+					Disconnected:    usageLog.Disconnected,
+				}
+			}
+
+			usageLogs = append(usageLogs, item)
+		}
+
+		if lastUsed > 0 {
+			keyUsages = append(keyUsages, &model.ApiKeyUsage{
+				ApiKey:     keyUsageLogs.ApiKey,
+				LastUsedAt: time.UnixMilli(lastUsed),
+				LastUsedIP: keyUsageLogs.LastUsedIP,
+			})
+		}
+	}
+
+	batch := &model.UsageBatch{Reference: req.BatchID, ApiServiceID: req.ApiServiceID}
+	if err := service.UsageLog().Report(ctx, batch, usageLogs); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	service.ApiKey().TouchAsync(ctx, keyUsages)
+
+	h.SetResponseData(UsageReportResponse{Count: len(usageLogs)})
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"common"
+	"common/secure"
+	"fmt"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按密钥所属工作空间汇总使用量，用于只读密钥查询
+
+type UsageSummaryHandler struct {
+	rest.Handler[UsageSummaryRequest]
+}
+
+type UsageSummaryRequest struct {
+	ID string `form:"id" binding:"required"`
+
+	model.UsageSearchParam
+}
+
+// 请求日志中不输出完整的API密钥
+func (r UsageSummaryRequest) String() string {
+	return fmt.Sprintf("{ID:%s %+v}", secure.MaskApiKey(r.ID), r.UsageSearchParam)
+}
+
+type UsageSummaryResponse struct {
+	TotalCount int                `json:"totalCount"`
+	PageIndex  int                `json:"pageIndex"`
+	PageSize   int                `json:"pageSize"`
+	Stats      []*model.UsageStat `json:"stats,omitempty"`
+}
+
+func NewUsageSummaryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &UsageSummaryHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *UsageSummaryHandler) Handle() {
+	req := &h.Request
+	ctx := h.GetContext()
+
+	apiKey, err := service.ApiKey().FindByKey(ctx, req.ID)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	// 只能查询密钥所属的工作空间
+	param := &req.UsageSearchParam
+	param.UserID = apiKey.UserID
+	param.WorkspaceID = apiKey.WorkspaceID
+	param.Bucket = ""
+
+	if param.PageIndex <= 0 {
+		param.PageIndex = 1
+	}
+
+	if param.PageSize <= 0 {
+		param.PageSize = 10
+	}
+
+	stats, total, err := service.UsageLog().Stats(ctx, param)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(UsageSummaryResponse{
+		TotalCount: total,
+		PageIndex:  param.PageIndex,
+		PageSize:   param.PageSize,
+		Stats:      stats,
+	})
+}
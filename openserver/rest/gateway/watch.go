@@ -0,0 +1,45 @@
+package gateway
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 监听变更事件，用于API网关及时清理缓存
+
+type WatchHandler struct {
+	rest.Handler[WatchRequest]
+}
+
+type WatchRequest struct {
+	Revision int64 `form:"revision" binding:"min=-1"`                // 已处理的版本号，-1表示只查询当前版本
+	Timeout  int   `form:"timeout" binding:"omitempty,min=1,max=60"` // 没有事件时最长等待秒数，默认10秒
+}
+
+func NewWatchHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &WatchHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *WatchHandler) Handle() {
+	req := h.Request
+
+	if req.Timeout <= 0 {
+		req.Timeout = 10
+	}
+
+	changeSet, err := service.ChangeEvent().Watch(h.GetContext(), req.Revision, time.Duration(req.Timeout)*time.Second)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(changeSet)
+}
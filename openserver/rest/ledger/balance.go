@@ -0,0 +1,35 @@
+package ledger
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询账户余额
+
+type BalanceHandler struct {
+	rest.Handler[BalanceRequest]
+}
+
+type BalanceRequest struct{}
+
+func NewBalanceHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &BalanceHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *BalanceHandler) Handle() {
+	account, err := service.Ledger().GetAccount(h.GetContext(), h.GetFromUser())
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(account)
+}
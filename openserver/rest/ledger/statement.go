@@ -0,0 +1,37 @@
+package ledger
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询月度账单
+
+type StatementHandler struct {
+	rest.Handler[StatementRequest]
+}
+
+type StatementRequest struct {
+	Month string `form:"month"` // 格式 2006-01，为空时为当月
+}
+
+func NewStatementHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &StatementHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *StatementHandler) Handle() {
+	statement, err := service.Ledger().Statement(h.GetContext(), h.GetFromUser(), h.Request.Month)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(statement)
+}
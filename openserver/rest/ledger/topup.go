@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"common"
+	"common/types"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用户充值，由零极云调用
+
+type TopUpHandler struct {
+	rest.Handler[TopUpRequest]
+}
+
+type TopUpRequest struct {
+	UserID      string       `json:"userID" binding:"required"`
+	Amount      types.Amount `json:"amount"`
+	Reference   string       `json:"reference" binding:"required"` // 充值单号，重复提交只入账一次
+	Description string       `json:"description,omitempty"`
+}
+
+func NewTopUpHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &TopUpHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *TopUpHandler) Handle() {
+	req := h.Request
+	transaction := &model.LedgerTransaction{
+		UserID:      req.UserID,
+		Amount:      req.Amount,
+		Reference:   req.Reference,
+		Description: req.Description,
+	}
+
+	if err := service.Ledger().TopUp(h.GetContext(), transaction); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(transaction)
+}
@@ -0,0 +1,59 @@
+package ledger
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询账务流水
+
+type TransactionsHandler struct {
+	rest.Handler[model.LedgerSearchParam]
+}
+
+type TransactionsResponse struct {
+	TotalCount   int                        `json:"totalCount"`
+	PageIndex    int                        `json:"pageIndex"`
+	PageSize     int                        `json:"pageSize"`
+	Transactions []*model.LedgerTransaction `json:"transactions,omitempty"`
+}
+
+func NewTransactionsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &TransactionsHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *TransactionsHandler) Handle() {
+	req := &h.Request
+	req.UserID = h.GetFromUser()
+
+	if req.PageIndex <= 0 {
+		req.PageIndex = 1
+	}
+
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	transactions, total, err := service.Ledger().ListTransactions(h.GetContext(), req)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	response := TransactionsResponse{
+		TotalCount:   total,
+		PageIndex:    req.PageIndex,
+		PageSize:     req.PageSize,
+		Transactions: transactions,
+	}
+
+	h.SetResponseData(response)
+}
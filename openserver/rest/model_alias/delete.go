@@ -0,0 +1,35 @@
+package model_alias
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 删除模型别名
+
+type DeleteHandler struct {
+	rest.Handler[DeleteRequest]
+}
+
+type DeleteRequest struct {
+	Alias string `form:"alias" binding:"required"`
+}
+
+func NewDeleteHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &DeleteHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *DeleteHandler) Handle() {
+	req := h.Request
+	if err := service.ModelAlias().Delete(h.GetContext(), req.Alias); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
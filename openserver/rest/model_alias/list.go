@@ -0,0 +1,40 @@
+package model_alias
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询模型别名
+
+type ListHandler struct {
+	rest.Handler[ListRequest]
+}
+
+type ListRequest struct{}
+
+type ListResponse struct {
+	Aliases []*model.ModelAlias `json:"aliases,omitempty"`
+}
+
+func NewListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ListHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *ListHandler) Handle() {
+	aliases, err := service.ModelAlias().List(h.GetContext())
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(ListResponse{Aliases: aliases})
+}
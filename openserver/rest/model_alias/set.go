@@ -0,0 +1,48 @@
+package model_alias
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 新增或修改模型别名
+
+type SetHandler struct {
+	rest.Handler[SetRequest]
+}
+
+type SetRequest struct {
+	Alias           string `json:"alias" binding:"required"`
+	ModelName       string `json:"modelName" binding:"required"`
+	RewriteResponse bool   `json:"rewriteResponse"`
+	Description     string `json:"description,omitempty"`
+}
+
+func NewSetHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SetHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SetHandler) Handle() {
+	req := h.Request
+	modelAlias := &model.ModelAlias{
+		Alias:           req.Alias,
+		ModelName:       req.ModelName,
+		RewriteResponse: req.RewriteResponse,
+		Description:     req.Description,
+	}
+
+	if err := service.ModelAlias().Set(h.GetContext(), modelAlias); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(modelAlias)
+}
@@ -0,0 +1,63 @@
+package platform_model
+
+import (
+	"common"
+	"common/types"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 新增模型价格版本
+
+type PriceCreateHandler struct {
+	rest.Handler[PriceCreateRequest]
+}
+
+type PriceCreateRequest struct {
+	ModelName        string       `json:"modelName" binding:"required"`
+	Currency         string       `json:"currency,omitempty"`
+	InputPrice       types.Amount `json:"inputPrice"`
+	CachedInputPrice types.Amount `json:"cachedInputPrice"`
+	OutputPrice      types.Amount `json:"outputPrice"`
+	ImagePrice       types.Amount `json:"imagePrice"`
+	AudioPrice       types.Amount `json:"audioPrice"`
+	CharacterPrice   types.Amount `json:"characterPrice"`
+	EffectiveAt      *time.Time   `json:"effectiveAt,omitempty"`
+}
+
+func NewPriceCreateHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &PriceCreateHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *PriceCreateHandler) Handle() {
+	req := h.Request
+	price := &model.ModelPrice{
+		ModelName:        req.ModelName,
+		Currency:         req.Currency,
+		InputPrice:       req.InputPrice,
+		CachedInputPrice: req.CachedInputPrice,
+		OutputPrice:      req.OutputPrice,
+		ImagePrice:       req.ImagePrice,
+		AudioPrice:       req.AudioPrice,
+		CharacterPrice:   req.CharacterPrice,
+	}
+
+	if req.EffectiveAt != nil {
+		price.EffectiveAt = *req.EffectiveAt
+	}
+
+	if err := service.ModelPrice().Create(h.GetContext(), price); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(price)
+}
@@ -0,0 +1,35 @@
+package platform_model
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 删除模型价格版本
+
+type PriceDeleteHandler struct {
+	rest.Handler[PriceDeleteRequest]
+}
+
+type PriceDeleteRequest struct {
+	ID int64 `form:"id" binding:"required"`
+}
+
+func NewPriceDeleteHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &PriceDeleteHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *PriceDeleteHandler) Handle() {
+	req := h.Request
+	if err := service.ModelPrice().Delete(h.GetContext(), req.ID); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
@@ -0,0 +1,43 @@
+package platform_model
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询模型的价格版本
+
+type PriceListHandler struct {
+	rest.Handler[PriceListRequest]
+}
+
+type PriceListRequest struct {
+	ModelName string `form:"modelName" binding:"required"`
+}
+
+type PriceListResponse struct {
+	Prices []*model.ModelPrice `json:"prices,omitempty"`
+}
+
+func NewPriceListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &PriceListHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *PriceListHandler) Handle() {
+	req := h.Request
+	prices, err := service.ModelPrice().List(h.GetContext(), req.ModelName)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(PriceListResponse{Prices: prices})
+}
@@ -17,6 +17,7 @@ type DeployRequest struct {
 	Name      string                    `json:"name" binding:"required"`
 	TopoID    uint64                    `json:"topoID" binding:"required"`
 	ModelName string                    `json:"modelName" binding:"required"`
+	Engine    string                    `json:"engine,omitempty"` // 推理引擎，为空时使用首选引擎
 	EipInfo   *resource_service.EipInfo `json:"eipInfo,omitempty"`
 }
 
@@ -37,7 +38,7 @@ func (h *DeploylHandler) Handle() {
 	svc := service.PlatformService()
 	svc.EipInfo = req.EipInfo
 
-	id, err := svc.Create(h.GetContext(), req.TopoID, req.Name, req.ModelName)
+	id, err := svc.Create(h.GetContext(), req.TopoID, req.Name, req.ModelName, req.Engine)
 	if err != nil {
 		h.SetErrorWithDefaultCode(err, common.Failure)
 		return
@@ -0,0 +1,35 @@
+package platform_service
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 删除模型的流量分配规则
+
+type SplitDeleteHandler struct {
+	rest.Handler[SplitDeleteRequest]
+}
+
+type SplitDeleteRequest struct {
+	ModelName string `form:"modelName" binding:"required"`
+}
+
+func NewSplitDeleteHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SplitDeleteHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SplitDeleteHandler) Handle() {
+	req := h.Request
+	if err := service.TrafficSplit().Delete(h.GetContext(), req.ModelName); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
@@ -0,0 +1,43 @@
+package platform_service
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询流量分配规则
+
+type SplitListHandler struct {
+	rest.Handler[SplitListRequest]
+}
+
+type SplitListRequest struct {
+	ModelName string `form:"modelName"`
+}
+
+type SplitListResponse struct {
+	Splits []*model.TrafficSplit `json:"splits,omitempty"`
+}
+
+func NewSplitListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SplitListHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SplitListHandler) Handle() {
+	req := h.Request
+	splits, err := service.TrafficSplit().List(h.GetContext(), req.ModelName)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(SplitListResponse{Splits: splits})
+}
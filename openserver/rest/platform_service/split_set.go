@@ -0,0 +1,48 @@
+package platform_service
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 设置模型的流量分配规则，整体替换已有规则
+
+type SplitSetHandler struct {
+	rest.Handler[SplitSetRequest]
+}
+
+type SplitSetRequest struct {
+	ModelName string                `json:"modelName" binding:"required"`
+	Routes    []*model.TrafficRoute `json:"routes,omitempty"`
+	Sticky    bool                  `json:"sticky"`
+	Shadow    *model.TrafficShadow  `json:"shadow,omitempty"`
+}
+
+func NewSplitSetHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SplitSetHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SplitSetHandler) Handle() {
+	req := h.Request
+	split := &model.TrafficSplit{
+		ModelName: req.ModelName,
+		Routes:    req.Routes,
+		Sticky:    req.Sticky,
+		Shadow:    req.Shadow,
+	}
+
+	if err := service.TrafficSplit().Set(h.GetContext(), split); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(split)
+}
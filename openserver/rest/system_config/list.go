@@ -0,0 +1,40 @@
+package system_config
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 查询系统配置，敏感配置只返回主密钥版本
+
+type ListHandler struct {
+	rest.Handler[ListRequest]
+}
+
+type ListRequest struct{}
+
+type ListResponse struct {
+	Configs []*model.SystemConfig `json:"configs,omitempty"`
+}
+
+func NewListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &ListHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *ListHandler) Handle() {
+	configs, err := service.SystemConfig().List(h.GetContext())
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	h.SetResponseData(ListResponse{Configs: configs})
+}
@@ -0,0 +1,46 @@
+package system_config
+
+import (
+	"common"
+	"fmt"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 保存系统配置，由零极云调用
+
+type SetHandler struct {
+	rest.Handler[SetRequest]
+}
+
+type SetRequest struct {
+	Key    string `json:"key" binding:"required"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"` // 敏感配置，信封加密后保存
+}
+
+// 请求日志中不输出敏感配置的值
+func (r SetRequest) String() string {
+	if r.Secret {
+		return fmt.Sprintf("{Key:%s Secret:true}", r.Key)
+	}
+	return fmt.Sprintf("{Key:%s Value:%s}", r.Key, r.Value)
+}
+
+func NewSetHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SetHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SetHandler) Handle() {
+	req := h.Request
+	if err := service.SystemConfig().Set(h.GetContext(), req.Key, req.Value, req.Secret); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
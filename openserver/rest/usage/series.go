@@ -0,0 +1,56 @@
+package usage
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按分钟、小时或天统计使用量趋势
+
+type SeriesHandler struct {
+	rest.Handler[model.UsageSearchParam]
+}
+
+func NewSeriesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SeriesHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SeriesHandler) Handle() {
+	req := &h.Request
+	req.UserID = h.GetFromUser()
+
+	if req.Bucket == "" {
+		req.Bucket = model.UsageBucketHour
+	}
+
+	if req.PageIndex <= 0 {
+		req.PageIndex = 1
+	}
+
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	stats, total, err := service.UsageLog().Stats(h.GetContext(), req)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	response := ListResponse{
+		TotalCount: total,
+		PageIndex:  req.PageIndex,
+		PageSize:   req.PageSize,
+		Stats:      stats,
+	}
+
+	h.SetResponseData(response)
+}
@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"common"
+	"openserver/model"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按工作空间、密钥或模型汇总使用量
+
+type SummaryHandler struct {
+	rest.Handler[model.UsageSearchParam]
+}
+
+type ListResponse struct {
+	TotalCount int                `json:"totalCount"`
+	PageIndex  int                `json:"pageIndex"`
+	PageSize   int                `json:"pageSize"`
+	Stats      []*model.UsageStat `json:"stats,omitempty"`
+}
+
+func NewSummaryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &SummaryHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *SummaryHandler) Handle() {
+	req := &h.Request
+	req.UserID = h.GetFromUser()
+	req.Bucket = ""
+
+	if req.PageIndex <= 0 {
+		req.PageIndex = 1
+	}
+
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	stats, total, err := service.UsageLog().Stats(h.GetContext(), req)
+	if err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+
+	response := ListResponse{
+		TotalCount: total,
+		PageIndex:  req.PageIndex,
+		PageSize:   req.PageSize,
+		Stats:      stats,
+	}
+
+	h.SetResponseData(response)
+}
@@ -0,0 +1,36 @@
+package workspace
+
+import (
+	"common"
+	"openserver/rest"
+	"openserver/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 设置工作空间服务等级，由零极云调用
+
+type TierUpdateHandler struct {
+	rest.Handler[TierUpdateRequest]
+}
+
+type TierUpdateRequest struct {
+	ID   string `json:"id" binding:"required"`
+	Tier int    `json:"tier"` // 0: 基础, 1: 标准, 2: 高级
+}
+
+func NewTierUpdateHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := &TierUpdateHandler{}
+		h.SetTaskHandler(h)
+		h.OnRequest(c)
+	}
+}
+
+func (h *TierUpdateHandler) Handle() {
+	req := h.Request
+	if err := service.Workspace().UpdateTier(h.GetContext(), req.ID, req.Tier); err != nil {
+		h.SetErrorWithDefaultCode(err, common.Failure)
+		return
+	}
+}
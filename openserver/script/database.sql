@@ -7,7 +7,7 @@
 DROP TABLE IF EXISTS system_configs;
 CREATE TABLE system_configs (  
     key TEXT PRIMARY KEY, -- 配置项名称
-    value TEXT NOT NULL, -- 配置项值
+    value TEXT NOT NULL, -- 配置项值，敏感配置保存信封加密的密文: enc:<主密钥版本>:<数据密钥>:<数据>
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
@@ -109,6 +109,34 @@ CREATE TABLE platform_models (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+/* 模型别名表，调用时别名解析为实际模型 */
+DROP TABLE IF EXISTS model_aliases;
+CREATE TABLE model_aliases (
+    alias TEXT PRIMARY KEY, -- 别名，如 qwen-chat、gpt-4o-mini
+    model_name TEXT NOT NULL, -- 实际模型名称
+    rewrite_response BOOLEAN DEFAULT FALSE, -- 是否将响应中的 model 字段改写为别名
+    description TEXT, -- 描述
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+/* 模型价格表，同一模型按生效时间保存多个版本 */
+DROP TABLE IF EXISTS model_prices;
+CREATE TABLE model_prices (
+    id BIGSERIAL PRIMARY KEY,
+    model_name TEXT NOT NULL, -- 模型名称
+    currency TEXT NOT NULL DEFAULT 'CNY', -- 币种
+    input_price NUMERIC(20, 8) DEFAULT 0, -- 每百万输入token价格
+    cached_input_price NUMERIC(20, 8) DEFAULT 0, -- 每百万缓存命中的输入token价格
+    output_price NUMERIC(20, 8) DEFAULT 0, -- 每百万输出token价格
+    image_price NUMERIC(20, 8) DEFAULT 0, -- 每张图片价格，按 1024x1024 分辨率计，其他分辨率按像素折算
+    audio_price NUMERIC(20, 8) DEFAULT 0, -- 每秒音频价格
+    character_price NUMERIC(20, 8) DEFAULT 0, -- 每万字符价格
+    effective_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- 生效时间
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE (model_name, effective_at)
+);
+
 /* 平台模型服务表 */
 DROP TABLE IF EXISTS platform_services;
 CREATE TABLE platform_services (
@@ -116,6 +144,7 @@ CREATE TABLE platform_services (
 	name TEXT NOT NULL, -- 服务名称
 	topo_id BIGINT NOT NULL, -- 所属拓扑域
     model_name TEXT NOT NULL, -- 模型名称
+    engine TEXT, -- 推理引擎名称
     api_service_id TEXT NOT NULL, -- API网关服务
     power BIGINT NOT NULL DEFAULT 0, -- 部署的算力
     load BIGINT NOT NULL DEFAULT 0, -- 平均负载
@@ -123,6 +152,17 @@ CREATE TABLE platform_services (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+/* 模型流量分配表，按服务ID或推理引擎分配流量，并可将部分请求镜像到候选服务 */
+DROP TABLE IF EXISTS traffic_splits;
+CREATE TABLE traffic_splits (
+    model_name TEXT PRIMARY KEY, -- 模型名称
+    routes JSONB, -- 流量分配规则：服务ID或推理引擎及权重
+    sticky BOOLEAN DEFAULT FALSE, -- 按API密钥保持分配结果
+    shadow JSONB, -- 镜像规则：候选服务ID或推理引擎及镜像比例
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
 /* API网关服务表 */
 DROP TABLE IF EXISTS api_services;
 CREATE TABLE api_services (
@@ -145,6 +185,32 @@ CREATE TABLE users (
     updated_at TIMESTAMPTZ DEFAULT NOW()
 );
 
+/* 用户账户表 */
+DROP TABLE IF EXISTS accounts;
+CREATE TABLE accounts (
+    user_id TEXT PRIMARY KEY, -- 用户ID
+    balance NUMERIC(20, 8) NOT NULL DEFAULT 0, -- 预付费余额
+    currency TEXT NOT NULL DEFAULT 'CNY', -- 币种
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+/* 账务流水表，只追加不修改 */
+DROP TABLE IF EXISTS ledger_transactions;
+CREATE TABLE ledger_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL, -- 用户ID
+    type TEXT NOT NULL, -- 类型: topup充值, usage调用扣费, adjust人工调整
+    amount NUMERIC(20, 8) NOT NULL, -- 变动金额，正数入账，负数扣费
+    balance NUMERIC(20, 8) NOT NULL, -- 变动后余额
+    reference TEXT, -- 充值单号或扣费批次
+    description TEXT, -- 描述
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_ledger_transactions_user ON ledger_transactions (user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_ledger_transactions_reference ON ledger_transactions (user_id, type, reference) WHERE reference IS NOT NULL;
+
 /* 用户工作空间表 */
 DROP TABLE IF EXISTS workspaces;
 CREATE TABLE workspaces (
@@ -152,6 +218,7 @@ CREATE TABLE workspaces (
     user_id TEXT NOT NULL, -- 用户ID
     name TEXT NOT NULL, -- 工作空间名称
     status TEXT DEFAULT 'enabled', -- 状态: enabled, disabled
+    tier SMALLINT NOT NULL DEFAULT 0, -- 服务等级: 0 基础, 1 标准, 2 高级，排队时等级高的优先
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW(),
     UNIQUE (user_id, name)
@@ -172,31 +239,81 @@ CREATE TABLE usage_limits (
 /* API密钥表 */
 DROP TABLE IF EXISTS api_keys;
 CREATE TABLE api_keys (
-    id TEXT PRIMARY KEY,  -- API密钥
+    id TEXT PRIMARY KEY,  -- API密钥的HMAC查找哈希，密钥本身不保存
+    prefix TEXT, -- 遮盖后的密钥，如 sk-abc…xyz，为空表示尚未迁移的旧数据
     user_id TEXT NOT NULL, -- 用户ID
     workspace_id TEXT NOT NULL, -- 所属工作空间ID
     description TEXT, -- 描述
     expires_at TIMESTAMPTZ, -- 过期时间，NULL 表示永不过期
+    scopes TEXT[], -- 允许调用的接口: chat, embeddings, rerank, classify, tokenize, audio, images, models, usage，NULL 表示不限制
+    models TEXT[], -- 允许调用的模型，NULL 表示工作空间已授权的全部模型
+    allowed_ips TEXT[], -- 允许的来源地址(CIDR)，NULL 表示不限制
+    request_limit BIGINT DEFAULT 0, -- 密钥请求数限流（次/分钟），0 表示不限制
+    token_limit BIGINT DEFAULT 0, -- 密钥Token限流（Tokens/分钟），0 表示不限制
+    disabled BOOLEAN DEFAULT FALSE, -- 是否停用
+    last_used_at TIMESTAMPTZ, -- 最近调用时间，由网关上报异步更新
+    last_used_ip TEXT, -- 最近调用的来源地址
     created_at TIMESTAMPTZ DEFAULT NOW(),
     updated_at TIMESTAMPTZ DEFAULT NOW()
 
 );
 
+/* 调用日志上报批次表，网关重试时按批次去重，批次ID同时作为扣费流水的单号 */
+DROP TABLE IF EXISTS usage_batches;
+CREATE TABLE usage_batches (
+    reference TEXT PRIMARY KEY, -- 批次ID，由网关生成，重试时不变
+    api_service_id TEXT, -- 上报的API网关
+    log_count INT NOT NULL DEFAULT 0, -- 日志数量
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_usage_batches_created ON usage_batches (created_at); -- 按时间清理，保留7天
+
 /* 调用统计表 */
 DROP TABLE IF EXISTS usage_logs;
 CREATE TABLE usage_logs (
     id BIGSERIAL,
-    api_key TEXT NOT NULL, -- 调用密钥
+    api_key TEXT NOT NULL, -- 调用密钥的查找哈希
     user_id TEXT NOT NULL, -- 用户ID
-    service_id BIGINT NOT NULL, -- 服务ID
+    workspace_id TEXT NOT NULL, -- 工作空间ID
+    model_name TEXT NOT NULL, -- 模型名称
+    service_id TEXT, -- 模型服务ID，未转发时为空
+    target TEXT, -- 最终处理请求的副本地址，未转发时为空
+    attempts SMALLINT DEFAULT 0, -- 转发尝试次数，大于1表示发生过重试
     occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), -- 精确到毫秒
-    status SMALLINT DEFAULT 0, -- 调用状态
+    status SMALLINT DEFAULT 0, -- 调用状态: 0成功, 1失败
     input_tokens BIGINT DEFAULT 0, -- 输入token数量
     output_tokens BIGINT DEFAULT 0, -- 输出token数量
+    cached_tokens BIGINT DEFAULT 0, -- 命中缓存的输入token数量，已包含在输入token中
 	response_time_ms INT NOT NULL,  -- 响应耗时(毫秒)
+    image_count INT DEFAULT 0, -- 生成图片数量
+    image_pixels BIGINT DEFAULT 0, -- 生成图片总像素，数量乘以分辨率
+    audio_ms BIGINT DEFAULT 0, -- 音频时长(毫秒)
+    characters BIGINT DEFAULT 0, -- 语音合成的字符数
+    estimated BOOLEAN DEFAULT FALSE, -- 使用量由网关统计，而非上游返回
+    first_token_ms INT, -- 首个内容块耗时(毫秒)，非流式调用为空
+    stream_duration_ms INT, -- 流式响应总耗时(毫秒)，非流式调用为空
+    tokens_per_second REAL, -- 输出速度，非流式调用为空
+    client_disconnected BOOLEAN, -- 客户端中途断开，非流式调用为空
+    price_id BIGINT, -- 计费使用的价格版本，未定价时为空
+    cost NUMERIC(20, 8) DEFAULT 0, -- 调用费用
     PRIMARY KEY (id, occurred_at)
 );
 
 SELECT create_hypertable('usage_logs', 'occurred_at');
 
+CREATE INDEX idx_usage_logs_user ON usage_logs (user_id, occurred_at DESC);
+CREATE INDEX idx_usage_logs_workspace ON usage_logs (workspace_id, occurred_at DESC);
+
+
+
+/* 变更事件表，API网关按版本号监听后刷新缓存 */
+DROP TABLE IF EXISTS change_events;
+CREATE TABLE change_events (
+    revision BIGSERIAL PRIMARY KEY, -- 版本号，单调递增
+    kind TEXT NOT NULL, -- 变更类型: key, workspace, user, service
+    target TEXT, -- 变更对象ID，密钥为查找哈希
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
 
+CREATE INDEX idx_change_events_created ON change_events (created_at);
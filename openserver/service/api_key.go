@@ -2,11 +2,15 @@ package service
 
 import (
 	"common"
+	"common/logger"
 	"common/secure"
 	"context"
 	"errors"
+	"fmt"
+	"openserver/config"
 	"openserver/model"
 	"openserver/repository"
+	"slices"
 	"time"
 )
 
@@ -16,15 +20,14 @@ func ApiKey() *ApiKeyService {
 	return &ApiKeyService{}
 }
 
-// 查询指定密钥
-func (s *ApiKeyService) FindByID(ctx context.Context, id string) (*model.ApiKey, error) {
-
-	cipherText, err := secure.Encrypt(id)
-	if err != nil {
-		return nil, err
-	}
+// 密钥的查找哈希
+func HashApiKey(apiKey string) string {
+	return secure.HashApiKey(config.GetSecure().KeyHashSecret, apiKey)
+}
 
-	apiKey, err := repository.ApiKey().GetByID(ctx, cipherText)
+// 按密钥查询，用于API网关调用
+func (s *ApiKeyService) FindByKey(ctx context.Context, key string) (*model.ApiKey, error) {
+	apiKey, err := repository.ApiKey().GetByID(ctx, HashApiKey(key))
 	if err != nil {
 		return nil, err
 	}
@@ -33,78 +36,259 @@ func (s *ApiKeyService) FindByID(ctx context.Context, id string) (*model.ApiKey,
 		return nil, &common.Error{Code: common.ApiKeyNotFound, Msg: "API KEY not found"}
 	}
 
-	apiKey.ID = id
+	if apiKey.Disabled {
+		return nil, &common.Error{Code: common.ApiKeyDisabled, Msg: "API KEY disabled"}
+	}
 
 	return apiKey, nil
 }
 
-// 查询用户密钥列表
+// 查询用户密钥列表，只返回遮盖后的密钥
 func (s *ApiKeyService) ListByUser(ctx context.Context, userID string, pageIndex, pageSize int) ([]*model.ApiKeyEx, int, error) {
-	apiKeys, totalCount, err := repository.ApiKey().ListByUser(ctx, userID, pageIndex, pageSize)
-	if err != nil {
-		return nil, 0, err
+	return repository.ApiKey().ListByUser(ctx, userID, pageIndex, pageSize)
+}
+
+// 检查密钥限制，模型须为工作空间已授权的模型
+func (s *ApiKeyService) checkRestriction(ctx context.Context, workspaceID string, restriction *model.ApiKeyRestriction) error {
+	if err := restriction.Check(); err != nil {
+		return &common.Error{Code: common.RequestParamError, Msg: err.Error()}
 	}
 
-	for _, key := range apiKeys {
-		plainText, err := secure.Decrypt(key.ID)
-		if err != nil {
-			return nil, 0, err
-		}
+	if len(restriction.Models) == 0 {
+		return nil
+	}
 
-		key.ID = plainText
+	usageLimits, err := Workspace().ListUsageLimits(ctx, workspaceID)
+	if err != nil {
+		return err
 	}
 
-	return apiKeys, totalCount, nil
+	for _, modelName := range restriction.Models {
+		if !slices.ContainsFunc(usageLimits, func(u *model.UsageLimit) bool { return u.ModelName == modelName }) {
+			return &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("model %s not granted to workspace", modelName)}
+		}
+	}
 
+	return nil
 }
 
-// 创建密钥
-func (s *ApiKeyService) Create(ctx context.Context, userID, workspaceID, description string, expiredAt *time.Time) (string, error) {
+// 创建密钥，密钥本身只在此时返回
+func (s *ApiKeyService) Create(ctx context.Context, userID, workspaceID, description string, expiredAt *time.Time, restriction *model.ApiKeyRestriction) (*model.ApiKey, string, error) {
 
 	// 判断工作空间是否属于该用户
 	workspace, err := Workspace().FindByID(ctx, workspaceID)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	if workspace.UserID != userID {
-		return "", errors.New("workspace id ownner error")
+		return nil, "", errors.New("workspace id ownner error")
 	}
 
-	// 先随机生成，再加密存储
-
-	plainText, err := secure.GenerateApiKey()
-	if err != nil {
-		return "", err
+	if err := s.checkRestriction(ctx, workspaceID, restriction); err != nil {
+		return nil, "", err
 	}
 
-	cipherText, err := secure.Encrypt(plainText)
-	if err != nil {
-		return "", err
-	}
+	// 先随机生成，只保存查找哈希和遮盖后的密钥
 
 	apiKey := &model.ApiKey{
-		ID:          cipherText,
 		UserID:      userID,
 		WorkspaceID: workspaceID,
 		Description: description,
 		ExpiresAt:   expiredAt,
+
+		ApiKeyRestriction: *restriction,
+	}
+
+	plainText, err := generateApiKey(apiKey)
+	if err != nil {
+		return nil, "", err
 	}
 
 	if err := repository.ApiKey().Create(ctx, apiKey); err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, plainText, nil
+}
+
+// 随机生成密钥，填充查找哈希和遮盖后的密钥
+func generateApiKey(apiKey *model.ApiKey) (string, error) {
+	plainText, err := secure.GenerateApiKey()
+	if err != nil {
 		return "", err
 	}
 
+	apiKey.ID = HashApiKey(plainText)
+	apiKey.Prefix = secure.MaskApiKey(plainText)
 	return plainText, nil
 }
 
-// 删除密钥
-func (s *ApiKeyService) Delete(ctx context.Context, id, userID string) error {
+// 轮换密钥的宽限期
+const (
+	DefaultRotateGrace = 24 * time.Hour
+	MaxRotateGrace     = 7 * 24 * time.Hour
+)
+
+// 轮换密钥，新密钥继承旧密钥的设置，宽限期内新旧密钥均可使用
+func (s *ApiKeyService) Rotate(ctx context.Context, id, userID string, grace time.Duration) (*model.ApiKey, string, error) {
+	if grace < 0 || grace > MaxRotateGrace {
+		return nil, "", &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("grace period must be between 0 and %s", MaxRotateGrace)}
+	}
+
+	old, err := repository.ApiKey().GetByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if old == nil || old.UserID != userID {
+		return nil, "", &common.Error{Code: common.ApiKeyNotFound, Msg: "API KEY not found"}
+	}
+
+	apiKey := &model.ApiKey{
+		UserID:      old.UserID,
+		WorkspaceID: old.WorkspaceID,
+		Description: old.Description,
+		ExpiresAt:   old.ExpiresAt,
+
+		ApiKeyRestriction: old.ApiKeyRestriction,
+	}
+
+	plainText, err := generateApiKey(apiKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	found, err := repository.ApiKey().Rotate(ctx, old.ID, userID, time.Now().Add(grace), apiKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !found {
+		return nil, "", &common.Error{Code: common.ApiKeyNotFound, Msg: "API KEY not found"}
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeKey, old.ID)
+	return apiKey, plainText, nil
+}
+
+// 停用或启用密钥，网关缓存过期后生效
+func (s *ApiKeyService) SetDisabled(ctx context.Context, id, userID string, disabled bool) error {
+	found, err := repository.ApiKey().SetDisabled(ctx, id, userID, disabled)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return &common.Error{Code: common.ApiKeyNotFound, Msg: "API KEY not found"}
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeKey, id)
+	return nil
+}
+
+// 查询指定天数内到期的密钥
+func (s *ApiKeyService) ListExpiring(ctx context.Context, userID string, days int) ([]*model.ApiKeyEx, error) {
+	return repository.ApiKey().ListExpiring(ctx, userID, time.Now().AddDate(0, 0, days))
+}
+
+// 异步更新密钥的最近调用，不影响调用日志的保存
+func (s *ApiKeyService) TouchAsync(ctx context.Context, usages []*model.ApiKeyUsage) {
+	if len(usages) == 0 {
+		return
+	}
+
+	for _, usage := range usages {
+		usage.ApiKey = HashApiKey(usage.ApiKey)
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		if err := repository.ApiKey().Touch(ctx, usages); err != nil {
+			logger.Warn("Update api key last used failed", logger.Err(err))
+		}
+	}()
+}
+
+// 修改密钥的描述、过期时间和限制，限制整体替换
+func (s *ApiKeyService) Update(ctx context.Context, id, userID, description string, expiredAt *time.Time, restriction *model.ApiKeyRestriction) (*model.ApiKey, error) {
+	apiKey, err := repository.ApiKey().GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == nil || apiKey.UserID != userID {
+		return nil, &common.Error{Code: common.ApiKeyNotFound, Msg: "API KEY not found"}
+	}
+
+	if err := s.checkRestriction(ctx, apiKey.WorkspaceID, restriction); err != nil {
+		return nil, err
+	}
+
+	apiKey.Description = description
+	apiKey.ExpiresAt = expiredAt
+	apiKey.ApiKeyRestriction = *restriction
 
-	cipherText, err := secure.Encrypt(id)
+	found, err := repository.ApiKey().Update(ctx, apiKey)
 	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, &common.Error{Code: common.ApiKeyNotFound, Msg: "API KEY not found"}
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeKey, apiKey.ID)
+	return apiKey, nil
+}
+
+// 删除密钥，ID为密钥列表返回的查找哈希
+func (s *ApiKeyService) Delete(ctx context.Context, id, userID string) error {
+	if err := repository.ApiKey().Delete(ctx, id, userID); err != nil {
 		return err
 	}
 
-	return repository.ApiKey().Delete(ctx, cipherText, userID)
+	ChangeEvent().Publish(ctx, model.ChangeKey, id)
+	return nil
+}
+
+// 将旧版加密保存的密钥迁移为查找哈希，返回迁移数量
+func (s *ApiKeyService) MigrateLegacy(ctx context.Context) (int, error) {
+	if err := repository.ApiKey().EnsurePrefixColumn(ctx); err != nil {
+		return 0, err
+	}
+
+	ids, err := repository.ApiKey().ListLegacyIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var migrations []*model.ApiKeyMigration
+	for _, id := range ids {
+		plainText, err := secure.Decrypt(id)
+		if err != nil {
+			logger.Warn("Skip undecryptable api key", logger.Err(err))
+			continue
+		}
+
+		migrations = append(migrations, &model.ApiKeyMigration{
+			OldID:  id,
+			NewID:  HashApiKey(plainText),
+			Prefix: secure.MaskApiKey(plainText),
+		})
+	}
+
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+
+	// 所有密钥在同一事务中迁移
+	if err := repository.ApiKey().Migrate(ctx, migrations); err != nil {
+		return 0, err
+	}
+
+	return len(migrations), nil
 }
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"common/logger"
+	"context"
+	"openserver/model"
+	"openserver/repository"
+	"sync"
+	"time"
+)
+
+// 变更事件保留时长，网关断开超过此时长后需要清空全部缓存
+const changeRetention = time.Hour
+
+// 单次监听返回的最大事件数量
+const changeBatchSize = 1000
+
+// 本实例发布事件时唤醒等待中的监听，其他实例的事件依靠定时查询
+var (
+	changeMutex  sync.Mutex
+	changeNotify = make(chan struct{})
+)
+
+type ChangeEventService struct{}
+
+func ChangeEvent() *ChangeEventService {
+	return &ChangeEventService{}
+}
+
+// 发布变更事件，失败时只记录日志，网关依靠定时刷新兜底
+func (s *ChangeEventService) Publish(ctx context.Context, kind, target string) {
+	ctx = context.WithoutCancel(ctx)
+	revision, err := repository.ChangeEvent().Create(ctx, kind, target)
+	if err != nil {
+		logger.Warn("Publish change event failed", logger.String("kind", kind), logger.Err(err))
+		return
+	}
+
+	changeMutex.Lock()
+	close(changeNotify)
+	changeNotify = make(chan struct{})
+	changeMutex.Unlock()
+
+	// 定期清理过期事件
+	if revision%100 == 0 {
+		if err := repository.ChangeEvent().DeleteBefore(ctx, time.Now().Add(-changeRetention)); err != nil {
+			logger.Warn("Cleanup change events failed", logger.Err(err))
+		}
+	}
+}
+
+// 等待指定版本之后的事件，超时返回空事件和当前版本，版本为负数时直接返回当前版本
+func (s *ChangeEventService) Watch(ctx context.Context, revision int64, wait time.Duration) (*model.ChangeSet, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		changeMutex.Lock()
+		notify := changeNotify
+		changeMutex.Unlock()
+
+		changeSet, err := s.changesAfter(ctx, revision)
+		if err != nil {
+			return nil, err
+		}
+
+		if revision < 0 || changeSet.Reset || len(changeSet.Events) > 0 {
+			return changeSet, nil
+		}
+
+		select {
+		case <-notify:
+		case <-ticker.C:
+		case <-timer.C:
+			return changeSet, nil
+		case <-ctx.Done():
+			return changeSet, nil
+		}
+	}
+}
+
+func (s *ChangeEventService) changesAfter(ctx context.Context, revision int64) (*model.ChangeSet, error) {
+	minRevision, maxRevision, err := repository.ChangeEvent().GetRange(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changeSet := &model.ChangeSet{Revision: maxRevision}
+	if revision < 0 || revision == maxRevision {
+		return changeSet, nil
+	}
+
+	// 事件已被清理或数据库已重建，无法增量同步
+	if revision < minRevision-1 || revision > maxRevision {
+		changeSet.Reset = true
+		return changeSet, nil
+	}
+
+	changeSet.Events, err = repository.ChangeEvent().ListAfter(ctx, revision, changeBatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if count := len(changeSet.Events); count > 0 {
+		changeSet.Revision = changeSet.Events[count-1].Revision
+	}
+
+	return changeSet, nil
+}
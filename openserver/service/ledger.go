@@ -0,0 +1,103 @@
+package service
+
+import (
+	"common"
+	"common/types"
+	"context"
+	"openserver/config"
+	"openserver/model"
+	"openserver/repository"
+	"time"
+)
+
+type LedgerService struct{}
+
+func Ledger() *LedgerService {
+	return &LedgerService{}
+}
+
+// 充值，同一充值单号只入账一次
+func (s *LedgerService) TopUp(ctx context.Context, transaction *model.LedgerTransaction) error {
+	if transaction.Amount.Sign() <= 0 {
+		return &common.Error{Code: common.RequestParamError, Msg: "amount must be positive"}
+	}
+
+	if _, err := User().FindByID(ctx, transaction.UserID); err != nil {
+		return err
+	}
+
+	transaction.Type = model.TransactionTopUp
+	if _, err := repository.Ledger().Apply(ctx, transaction); err != nil {
+		return err
+	}
+
+	// 余额耗尽的用户充值后立即恢复调用
+	ChangeEvent().Publish(ctx, model.ChangeUser, transaction.UserID)
+	return nil
+}
+
+// 查询账户余额，未开户时为空
+func (s *LedgerService) FindAccount(ctx context.Context, userID string) (*model.Account, error) {
+	return repository.Ledger().GetAccount(ctx, userID)
+}
+
+// 网关判断余额使用的余额，预付费时未开户视为零余额，否则未开户时为空表示不限制
+func (s *LedgerService) FindBalance(ctx context.Context, userID string) (*types.Amount, error) {
+	account, err := s.FindAccount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account != nil {
+		return &account.Balance, nil
+	}
+
+	if config.GetLedger().Prepaid {
+		return &types.Amount{}, nil
+	}
+
+	return nil, nil
+}
+
+// 查询账户，未开户时返回零余额
+func (s *LedgerService) GetAccount(ctx context.Context, userID string) (*model.Account, error) {
+	account, err := s.FindAccount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account == nil {
+		account = &model.Account{UserID: userID, Currency: model.DefaultCurrency}
+	}
+
+	return account, nil
+}
+
+// 查询流水
+func (s *LedgerService) ListTransactions(ctx context.Context, param *model.LedgerSearchParam) ([]*model.LedgerTransaction, int, error) {
+	return repository.Ledger().ListTransactions(ctx, param)
+}
+
+// 月度账单，月份格式 2006-01，为空时为当月
+func (s *LedgerService) Statement(ctx context.Context, userID, month string) (*model.Statement, error) {
+	now := time.Now()
+	startTime := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if month != "" {
+		var err error
+		startTime, err = time.ParseInLocation("2006-01", month, now.Location())
+		if err != nil {
+			return nil, &common.Error{Code: common.RequestParamError, Msg: "month must be in format 2006-01"}
+		}
+	}
+
+	statement := &model.Statement{
+		UserID: userID,
+		Month:  startTime.Format("2006-01"),
+	}
+
+	if err := repository.Ledger().Statement(ctx, statement, startTime, startTime.AddDate(0, 1, 0)); err != nil {
+		return nil, err
+	}
+
+	return statement, nil
+}
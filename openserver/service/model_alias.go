@@ -0,0 +1,64 @@
+package service
+
+import (
+	"common"
+	"context"
+	"openserver/model"
+	"openserver/repository"
+)
+
+type ModelAliasService struct{}
+
+func ModelAlias() *ModelAliasService {
+	return &ModelAliasService{}
+}
+
+// 新增或修改别名，目标必须是已存在的预置模型，别名不能与预置模型重名
+func (s *ModelAliasService) Set(ctx context.Context, modelAlias *model.ModelAlias) error {
+	if modelAlias.Alias == modelAlias.ModelName {
+		return &common.Error{Code: common.RequestParamError, Msg: "alias must differ from model name"}
+	}
+
+	pm, err := repository.PlatformModel().GetByModelName(ctx, modelAlias.Alias)
+	if err != nil {
+		return err
+	}
+	if pm != nil {
+		return &common.Error{Code: common.RequestParamError, Msg: "alias conflicts with platform model"}
+	}
+
+	pm, err = repository.PlatformModel().GetByModelName(ctx, modelAlias.ModelName)
+	if err != nil {
+		return err
+	}
+	if pm == nil {
+		return &common.Error{Code: common.PlatModelNotFound, Msg: "platform model not found"}
+	}
+
+	if err := repository.ModelAlias().Upsert(ctx, modelAlias); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, modelAlias.Alias)
+	return nil
+}
+
+// 删除别名
+func (s *ModelAliasService) Delete(ctx context.Context, alias string) error {
+	deleted, err := repository.ModelAlias().Delete(ctx, alias)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return &common.Error{Code: common.ModelAliasNotFound, Msg: "model alias not found"}
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, alias)
+	return nil
+}
+
+// 查询所有别名
+func (s *ModelAliasService) List(ctx context.Context) ([]*model.ModelAlias, error) {
+	return repository.ModelAlias().List(ctx)
+}
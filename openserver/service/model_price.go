@@ -0,0 +1,82 @@
+package service
+
+import (
+	"common"
+	"context"
+	"openserver/model"
+	"openserver/repository"
+	"time"
+)
+
+type ModelPriceService struct{}
+
+func ModelPrice() *ModelPriceService {
+	return &ModelPriceService{}
+}
+
+// 新增价格版本，生效时间为空时立即生效
+func (s *ModelPriceService) Create(ctx context.Context, price *model.ModelPrice) error {
+	pm, err := repository.PlatformModel().GetByModelName(ctx, price.ModelName)
+	if err != nil {
+		return err
+	}
+	if pm == nil {
+		return &common.Error{Code: common.PlatModelNotFound, Msg: "platform model not found"}
+	}
+
+	for _, amount := range []int{
+		price.InputPrice.Sign(),
+		price.CachedInputPrice.Sign(),
+		price.OutputPrice.Sign(),
+		price.ImagePrice.Sign(),
+		price.AudioPrice.Sign(),
+		price.CharacterPrice.Sign(),
+	} {
+		if amount < 0 {
+			return &common.Error{Code: common.RequestParamError, Msg: "price must not be negative"}
+		}
+	}
+
+	if price.Currency == "" {
+		price.Currency = model.DefaultCurrency
+	}
+
+	if price.EffectiveAt.IsZero() {
+		price.EffectiveAt = time.Now()
+	}
+
+	return repository.ModelPrice().Create(ctx, price)
+}
+
+// 删除价格版本
+func (s *ModelPriceService) Delete(ctx context.Context, id int64) error {
+	deleted, err := repository.ModelPrice().Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return &common.Error{Code: common.ModelPriceNotFound, Msg: "model price not found"}
+	}
+
+	return nil
+}
+
+// 查询模型的所有价格版本
+func (s *ModelPriceService) List(ctx context.Context, modelName string) ([]*model.ModelPrice, error) {
+	return repository.ModelPrice().ListByModels(ctx, []string{modelName})
+}
+
+// 查询多个模型的价格
+func (s *ModelPriceService) FindByModels(ctx context.Context, modelNames []string) (model.ModelPrices, error) {
+	prices, err := repository.ModelPrice().ListByModels(ctx, modelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	modelPrices := make(model.ModelPrices)
+	for _, price := range prices {
+		modelPrices[price.ModelName] = append(modelPrices[price.ModelName], price)
+	}
+	return modelPrices, nil
+}
@@ -22,12 +22,27 @@ func (r *PlatformModelService) List(ctx context.Context, searchParams *model.Pla
 	return repository.PlatformModel().List(ctx, searchParams)
 }
 
+// 查询API网关已部署的模型
+func (r *PlatformModelService) ListByGateway(ctx context.Context, apiServiceID string) ([]*model.PlatformModel, error) {
+	return repository.PlatformModel().ListByGateway(ctx, apiServiceID)
+}
+
 // 预置模型
 func (r *PlatformModelService) Create(ctx context.Context, pm *model.PlatformModel) error {
-	return repository.PlatformModel().Create(ctx, pm)
+	if err := repository.PlatformModel().Create(ctx, pm); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, pm.Name)
+	return nil
 }
 
 // 删除模型
 func (r *PlatformModelService) Delete(ctx context.Context, name string) error {
-	return repository.PlatformModel().Delete(ctx, name)
+	if err := repository.PlatformModel().Delete(ctx, name); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, name)
+	return nil
 }
@@ -36,13 +36,31 @@ func (s *PlatformServiceDefault) ListByGateway(ctx context.Context, apiServiceID
 		return nil, nil
 	}
 
+	// 并发上限来自预置模型的部署信息
+	maxInflights := make(map[string]int64)
+	for _, service := range services {
+		if _, ok := maxInflights[service.ModelName]; ok {
+			continue
+		}
+		platformModel, err := PlatformModel().FindByModelName(ctx, service.ModelName)
+		if err != nil {
+			return nil, err
+		}
+		maxInflights[service.ModelName] = 0
+		if platformModel != nil && platformModel.DeployInfo != nil {
+			maxInflights[service.ModelName] = platformModel.DeployInfo.MaxInflight
+		}
+	}
+
 	var infoList []*model.ModelServiceInfo
 	for _, service := range services {
 		infoList = append(infoList, &model.ModelServiceInfo{
-			ID:        service.ID,
-			ModelName: service.ModelName,
-			Power:     service.Power,
-			Load:      service.Load,
+			ID:          service.ID,
+			ModelName:   service.ModelName,
+			Engine:      service.Engine,
+			Power:       service.Power,
+			Load:        service.Load,
+			MaxInflight: maxInflights[service.ModelName],
 		})
 	}
 
@@ -98,7 +116,8 @@ func (s *PlatformServiceDefault) getServiceTargets(ctx context.Context, id strin
 	channel <- ServiceTargetResponse{ID: id, Targets: targets}
 }
 
-func (s *PlatformServiceDefault) Create(ctx context.Context, topoID uint64, serviceName, modelName string) (string, error) {
+// 部署模型服务，推理引擎为空时使用首选引擎
+func (s *PlatformServiceDefault) Create(ctx context.Context, topoID uint64, serviceName, modelName, engine string) (string, error) {
 
 	// 获取拓扑域AIP网关
 
@@ -113,7 +132,7 @@ func (s *PlatformServiceDefault) Create(ctx context.Context, topoID uint64, serv
 
 	// 创建平台模型服务
 
-	serviceID, err := s.createService(ctx, topoID, modelName)
+	serviceID, engine, err := s.createService(ctx, topoID, modelName, engine)
 	if err != nil {
 		return "", err
 	}
@@ -125,6 +144,7 @@ func (s *PlatformServiceDefault) Create(ctx context.Context, topoID uint64, serv
 		TopoID:       topoID,
 		Name:         serviceName,
 		ModelName:    modelName,
+		Engine:       engine,
 		ApiServiceID: apiService.ID,
 	}
 
@@ -132,52 +152,56 @@ func (s *PlatformServiceDefault) Create(ctx context.Context, topoID uint64, serv
 		return "", err
 	}
 
+	ChangeEvent().Publish(ctx, model.ChangeService, serviceID)
 	return serviceID, nil
 }
 
-func (s *PlatformServiceDefault) createService(ctx context.Context, topoID uint64, modelName string) (string, error) {
+func (s *PlatformServiceDefault) createService(ctx context.Context, topoID uint64, modelName, engine string) (string, string, error) {
 
 	// 获取平台预置模型信息
 
 	platormModel, err := PlatformModel().FindByModelName(ctx, modelName)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	if platormModel == nil {
-		return "", &common.Error{Code: common.PlatModelNotFound, Msg: "platform model not found"}
+		return "", "", &common.Error{Code: common.PlatModelNotFound, Msg: "platform model not found"}
 	}
 
 	deployInfo := platormModel.DeployInfo
 	if deployInfo == nil {
-		return "", &common.Error{Code: common.PlatModelNotFound, Msg: "platform deploy infomation not found"}
+		return "", "", &common.Error{Code: common.PlatModelNotFound, Msg: "platform deploy infomation not found"}
 	}
 
 	// 获取部署信息
 
-	inferInfo := deployInfo.GetPlatformInferInfo()
+	inferInfo := deployInfo.FindInferInfo(engine)
 	if inferInfo == nil {
-		return "", fmt.Errorf("not found any infer engine")
+		if engine != "" {
+			return "", "", &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("infer engine %s is not suitable for the model", engine)}
+		}
+		return "", "", fmt.Errorf("not found any infer engine")
 	}
 
 	inferGpu := inferInfo.GetPlatformInferGpu()
 	if inferGpu == nil {
-		return "", fmt.Errorf("not found any infer GPU")
+		return "", "", fmt.Errorf("not found any infer GPU")
 	}
 
 	inferEngine, err := InferEngine().FindByName(ctx, inferInfo.Name)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	if inferEngine == nil {
-		return "", fmt.Errorf("%s not exist", inferInfo.Name)
+		return "", "", fmt.Errorf("%s not exist", inferInfo.Name)
 	}
 
 	// 获取私有网络
 
 	vpcID, err := Topo().FetchVpcID(ctx, topoID)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// 创建平台服务
@@ -216,10 +240,10 @@ func (s *PlatformServiceDefault) createService(ctx context.Context, topoID uint6
 
 	serviceID, err := service.Create(ctx, &request)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return serviceID, nil
+	return serviceID, inferInfo.Name, nil
 
 }
 
@@ -227,5 +251,11 @@ func (s *PlatformServiceDefault) Release(ctx context.Context, id string) error {
 	if err := service.Release(ctx, id); err != nil {
 		return err
 	}
-	return repository.PlatormService().Delete(ctx, id)
+
+	if err := repository.PlatormService().Delete(ctx, id); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, id)
+	return nil
 }
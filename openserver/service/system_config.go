@@ -0,0 +1,133 @@
+package service
+
+import (
+	"common"
+	"common/secure"
+	"context"
+	"fmt"
+	"openserver/config"
+	"openserver/model"
+	"openserver/repository"
+	"strings"
+)
+
+// 系统配置引用前缀，如 sysconfig:cloud_dmapp_key，连接数据库后从系统配置读取
+const SystemConfigRefPrefix = "sysconfig:"
+
+type SystemConfigService struct{}
+
+func SystemConfig() *SystemConfigService {
+	return &SystemConfigService{}
+}
+
+// 查询配置值，敏感配置返回解密后的值，未找到时返回空
+func (s *SystemConfigService) GetValue(ctx context.Context, key string) (string, error) {
+	found, err := repository.SystemConfig().GetByKey(ctx, key)
+	if err != nil || found == nil {
+		return "", err
+	}
+
+	value := found.Value
+	if err := secure.DecryptSecrets(config.GetSecure().Keyring(), &value); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// 解析配置中的系统配置引用，其他值原样保留
+func (s *SystemConfigService) ResolveConfig(ctx context.Context, values ...*string) error {
+	for _, value := range values {
+		key, ok := strings.CutPrefix(*value, SystemConfigRefPrefix)
+		if !ok {
+			continue
+		}
+
+		resolved, err := s.GetValue(ctx, key)
+		if err != nil {
+			return fmt.Errorf("resolve system config %s: %w", key, err)
+		}
+		if resolved == "" {
+			return fmt.Errorf("resolve system config %s: not found", key)
+		}
+
+		*value = resolved
+	}
+
+	return nil
+}
+
+// 保存配置，敏感配置使用当前主密钥加密保存
+func (s *SystemConfigService) Set(ctx context.Context, key, value string, secret bool) error {
+	if secret {
+		keyring := config.GetSecure().Keyring()
+		if keyring == nil {
+			return &common.Error{Code: common.RequestParamError, Msg: "master key not configured"}
+		}
+
+		encrypted, err := keyring.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		value = encrypted
+	}
+
+	return repository.SystemConfig().Upsert(ctx, key, value)
+}
+
+// 配置列表，敏感配置只返回主密钥版本
+func (s *SystemConfigService) List(ctx context.Context) ([]*model.SystemConfig, error) {
+	configs, err := repository.SystemConfig().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, config := range configs {
+		if secure.IsEncrypted(config.Value) {
+			config.Secret = true
+			config.KeyVersion = secure.KeyVersion(config.Value)
+			config.Value = ""
+		}
+	}
+
+	return configs, nil
+}
+
+// 使用当前主密钥重新加密敏感配置，返回重新加密的数量，旧版本主密钥在完成前需保持可用
+func (s *SystemConfigService) RotateSecrets(ctx context.Context) (int, error) {
+	keyring := config.GetSecure().Keyring()
+	if keyring == nil {
+		return 0, &common.Error{Code: common.Failure, Msg: "master key not configured"}
+	}
+
+	configs, err := repository.SystemConfig().List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, config := range configs {
+		if !secure.IsEncrypted(config.Value) {
+			continue
+		}
+
+		encrypted, changed, err := keyring.Reencrypt(config.Value)
+		if err != nil {
+			return count, err
+		}
+		if !changed {
+			continue
+		}
+
+		// 期间被修改的配置已使用当前主密钥加密
+		swapped, err := repository.SystemConfig().CompareAndSwap(ctx, config.Key, config.Value, encrypted)
+		if err != nil {
+			return count, err
+		}
+		if swapped {
+			count++
+		}
+	}
+
+	return count, nil
+}
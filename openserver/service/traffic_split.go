@@ -0,0 +1,158 @@
+package service
+
+import (
+	"common"
+	"context"
+	"fmt"
+	"openserver/model"
+	"openserver/repository"
+	"slices"
+)
+
+type TrafficSplitService struct{}
+
+func TrafficSplit() *TrafficSplitService {
+	return &TrafficSplitService{}
+}
+
+// 设置模型的流量分配规则，服务ID必须属于该模型，推理引擎必须是模型支持的引擎
+func (s *TrafficSplitService) Set(ctx context.Context, split *model.TrafficSplit) error {
+	if err := split.Check(); err != nil {
+		return &common.Error{Code: common.RequestParamError, Msg: err.Error()}
+	}
+
+	pm, err := repository.PlatformModel().GetByModelName(ctx, split.ModelName)
+	if err != nil {
+		return err
+	}
+	if pm == nil {
+		return &common.Error{Code: common.PlatModelNotFound, Msg: "platform model not found"}
+	}
+
+	services, err := repository.PlatormService().ListByModel(ctx, split.ModelName)
+	if err != nil {
+		return err
+	}
+
+	var engines []string
+	if pm.DeployInfo != nil {
+		for _, inferInfo := range pm.DeployInfo.InferInfos {
+			engines = append(engines, inferInfo.Name)
+		}
+	}
+
+	check := func(serviceID, engine string) error {
+		if engine != "" {
+			if !slices.Contains(engines, engine) {
+				return &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("infer engine %s is not suitable for the model", engine)}
+			}
+			return nil
+		}
+		if !slices.ContainsFunc(services, func(service *model.PlatformService) bool { return service.ID == serviceID }) {
+			return &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("service %s does not belong to the model", serviceID)}
+		}
+		return nil
+	}
+
+	for _, route := range split.Routes {
+		if err := check(route.ServiceID, route.Engine); err != nil {
+			return err
+		}
+	}
+
+	if split.Shadow != nil {
+		if err := check(split.Shadow.ServiceID, split.Shadow.Engine); err != nil {
+			return err
+		}
+	}
+
+	if err := repository.TrafficSplit().Upsert(ctx, split); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, split.ModelName)
+	return nil
+}
+
+// 删除模型的流量分配规则，恢复为所有服务均分流量
+func (s *TrafficSplitService) Delete(ctx context.Context, modelName string) error {
+	deleted, err := repository.TrafficSplit().Delete(ctx, modelName)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return &common.Error{Code: common.TrafficSplitNotFound, Msg: "traffic split not found"}
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeService, modelName)
+	return nil
+}
+
+// 查询流量分配规则，模型名称为空时查询全部
+func (s *TrafficSplitService) List(ctx context.Context, modelName string) ([]*model.TrafficSplit, error) {
+	var modelNames []string
+	if modelName != "" {
+		modelNames = []string{modelName}
+	}
+	return repository.TrafficSplit().ListByModels(ctx, modelNames)
+}
+
+// 获取API网关负责的模型的流量分配规则，只包含网关上的服务，没有匹配服务的规则被忽略
+func (s *TrafficSplitService) ListByGateway(ctx context.Context, apiServiceID string) ([]*model.ModelTrafficSplit, error) {
+	services, err := repository.PlatormService().ListByGateway(ctx, apiServiceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	var modelNames []string
+	for _, service := range services {
+		if !slices.Contains(modelNames, service.ModelName) {
+			modelNames = append(modelNames, service.ModelName)
+		}
+	}
+
+	splits, err := repository.TrafficSplit().ListByModels(ctx, modelNames)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*model.ModelTrafficSplit
+	for _, split := range splits {
+		result := &model.ModelTrafficSplit{ModelName: split.ModelName, Sticky: split.Sticky}
+
+		for _, route := range split.Routes {
+			serviceIDs := matchServices(services, split.ModelName, route.Match)
+			if len(serviceIDs) > 0 {
+				result.Routes = append(result.Routes, &model.ModelTrafficRoute{ServiceIDs: serviceIDs, Weight: route.Weight})
+			}
+		}
+
+		if shadow := split.Shadow; shadow != nil {
+			serviceIDs := matchServices(services, split.ModelName, shadow.Match)
+			if len(serviceIDs) > 0 {
+				result.Shadow = &model.ModelTrafficShadow{ServiceIDs: serviceIDs, Percent: shadow.Percent}
+			}
+		}
+
+		if len(result.Routes) > 0 || result.Shadow != nil {
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+func matchServices(services []*model.PlatformService, modelName string, match func(*model.PlatformService) bool) []string {
+	var serviceIDs []string
+	for _, service := range services {
+		if service.ModelName == modelName && match(service) {
+			serviceIDs = append(serviceIDs, service.ID)
+		}
+	}
+	return serviceIDs
+}
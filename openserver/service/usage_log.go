@@ -0,0 +1,195 @@
+package service
+
+import (
+	"common"
+	"common/logger"
+	"context"
+	"fmt"
+	"openserver/model"
+	"openserver/repository"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 上报批次保留时长，需大于网关待上报批次的最长保留时长，期间重复上报的批次不会重复记录
+const usageBatchRetention = 7 * 24 * time.Hour
+
+// 上次清理过期批次的时间
+var lastBatchCleanup atomic.Int64
+
+type UsageLogService struct{}
+
+func UsageLog() *UsageLogService {
+	return &UsageLogService{}
+}
+
+// 保存网关上报的调用日志，批次ID为空时不去重
+func (s *UsageLogService) Report(ctx context.Context, batch *model.UsageBatch, usageLogs []*model.UsageLog) error {
+	if len(usageLogs) == 0 {
+		return nil
+	}
+
+	if batch.Reference == "" {
+		batch.Reference = uuid.New().String()
+	}
+
+	// 与密钥表一致，保存密钥的查找哈希
+	hashes := make(map[string]string)
+	for _, usageLog := range usageLogs {
+		hash, ok := hashes[usageLog.ApiKey]
+		if !ok {
+			hash = HashApiKey(usageLog.ApiKey)
+			hashes[usageLog.ApiKey] = hash
+		}
+		usageLog.ApiKey = hash
+	}
+
+	if err := s.computeCost(ctx, usageLogs); err != nil {
+		return err
+	}
+
+	debits := usageDebits(batch.Reference, usageLogs)
+	count, err := repository.UsageLog().CopyFrom(ctx, batch, usageLogs, debits)
+	if err != nil || count == 0 {
+		return err
+	}
+
+	// 每小时清理一次过期批次
+	now := time.Now()
+	if last := lastBatchCleanup.Load(); now.Sub(time.Unix(last, 0)) > time.Hour && lastBatchCleanup.CompareAndSwap(last, now.Unix()) {
+		if err := repository.UsageLog().DeleteBatchesBefore(ctx, now.Add(-usageBatchRetention)); err != nil {
+			logger.Warn("Cleanup usage batches failed", logger.Err(err))
+		}
+	}
+
+	// 本批扣费后余额耗尽的用户通知网关立即停止调用，不等待密钥缓存过期
+	for _, debit := range debits {
+		if debit.Balance.Sign() <= 0 && debit.Balance.Sub(debit.Amount).Sign() > 0 {
+			ChangeEvent().Publish(ctx, model.ChangeUser, debit.UserID)
+		}
+	}
+
+	return nil
+}
+
+// 按用户汇总本批调用费用，生成扣费流水，以批次ID作为单号
+func usageDebits(reference string, usageLogs []*model.UsageLog) []*model.LedgerTransaction {
+
+	var debits []*model.LedgerTransaction
+	costs := make(map[string]*model.LedgerTransaction)
+	for _, usageLog := range usageLogs {
+		if usageLog.Cost.Sign() <= 0 {
+			continue
+		}
+
+		debit := costs[usageLog.UserID]
+		if debit == nil {
+			debit = &model.LedgerTransaction{
+				UserID:      usageLog.UserID,
+				Type:        model.TransactionUsage,
+				Reference:   reference,
+				Description: "model usage",
+			}
+			costs[usageLog.UserID] = debit
+			debits = append(debits, debit)
+		}
+
+		debit.Amount = debit.Amount.Sub(usageLog.Cost)
+	}
+	return debits
+}
+
+// 按调用时生效的价格计算费用，未定价的模型费用为0
+func (s *UsageLogService) computeCost(ctx context.Context, usageLogs []*model.UsageLog) error {
+	var modelNames []string
+	for _, usageLog := range usageLogs {
+		if !slices.Contains(modelNames, usageLog.ModelName) {
+			modelNames = append(modelNames, usageLog.ModelName)
+		}
+	}
+
+	prices, err := ModelPrice().FindByModels(ctx, modelNames)
+	if err != nil {
+		return err
+	}
+
+	for _, usageLog := range usageLogs {
+		price := prices.Find(usageLog.ModelName, usageLog.OccurredAt)
+		if price == nil {
+			continue
+		}
+
+		usageLog.PriceID = &price.ID
+		usageLog.Cost = price.Cost(usageLog)
+	}
+
+	return nil
+}
+
+// 各时间粒度允许查询的最大时间范围
+var usageBucketMaxRanges = map[string]time.Duration{
+	model.UsageBucketMinute: 24 * time.Hour,
+	model.UsageBucketHour:   31 * 24 * time.Hour,
+	model.UsageBucketDay:    366 * 24 * time.Hour,
+}
+
+// 统计用户使用量
+func (s *UsageLogService) Stats(ctx context.Context, param *model.UsageSearchParam) ([]*model.UsageStat, int, error) {
+
+	switch param.GroupBy {
+	case "", model.UsageGroupWorkspace, model.UsageGroupKey, model.UsageGroupModel:
+	default:
+		return nil, 0, &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("invalid groupBy: %s", param.GroupBy)}
+	}
+
+	// 默认查询最近一天
+	if param.EndTime == nil {
+		endTime := time.Now()
+		param.EndTime = &endTime
+	}
+	if param.StartTime == nil {
+		startTime := param.EndTime.Add(-24 * time.Hour)
+		param.StartTime = &startTime
+	}
+	if !param.StartTime.Before(*param.EndTime) {
+		return nil, 0, &common.Error{Code: common.RequestParamError, Msg: "startTime must be before endTime"}
+	}
+
+	if param.Bucket != "" {
+		maxRange, ok := usageBucketMaxRanges[param.Bucket]
+		if !ok {
+			return nil, 0, &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("invalid bucket: %s", param.Bucket)}
+		}
+		if param.EndTime.Sub(*param.StartTime) > maxRange {
+			return nil, 0, &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("time range too large for bucket %s", param.Bucket)}
+		}
+	}
+
+	stats, total, err := repository.UsageLog().Stats(ctx, param)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 与密钥列表一致，返回遮盖后的密钥
+	var ids []string
+	for _, stat := range stats {
+		if stat.ApiKey != "" {
+			ids = append(ids, stat.ApiKey)
+		}
+	}
+
+	if len(ids) > 0 {
+		prefixes, err := repository.ApiKey().ListPrefixes(ctx, ids)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, stat := range stats {
+			stat.ApiKeyPrefix = prefixes[stat.ApiKey]
+		}
+	}
+
+	return stats, total, nil
+}
@@ -55,9 +55,33 @@ func (s *WorkspaceService) Create(ctx context.Context, userID, name string) (str
 	return workspace.ID, repository.Workspace().Create(ctx, &workspace)
 }
 
+// 设置服务等级
+func (s *WorkspaceService) UpdateTier(ctx context.Context, id string, tier int) error {
+	if tier < model.WorkspaceTierBasic || tier > model.WorkspaceTierPremium {
+		return &common.Error{Code: common.RequestParamError, Msg: fmt.Sprintf("invalid workspace tier: %d", tier)}
+	}
+
+	found, err := repository.Workspace().UpdateTier(ctx, id, tier)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return &common.Error{Code: common.WorkspaceNotFound, Msg: "workspace not found"}
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeWorkspace, id)
+	return nil
+}
+
 // 删除工作空间
 func (s *WorkspaceService) Delete(ctx context.Context, id string, userID string) error {
-	return repository.Workspace().Delete(ctx, id, userID)
+	if err := repository.Workspace().Delete(ctx, id, userID); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeWorkspace, id)
+	return nil
 }
 
 // 工作空间授权列表
@@ -79,10 +103,20 @@ func (s *WorkspaceService) GrantModel(ctx context.Context, usageLimit *model.Usa
 		err = usageLimitRepo.Update(ctx, usageLimit)
 	}
 
-	return err
+	if err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeWorkspace, usageLimit.WorkspaceID)
+	return nil
 }
 
 // 删除授权
 func (s *WorkspaceService) CancelModel(ctx context.Context, workspaceID, modelName string) error {
-	return repository.UsageLimit().Delete(ctx, workspaceID, modelName)
+	if err := repository.UsageLimit().Delete(ctx, workspaceID, modelName); err != nil {
+		return err
+	}
+
+	ChangeEvent().Publish(ctx, model.ChangeWorkspace, workspaceID)
+	return nil
 }